@@ -0,0 +1,161 @@
+// Package changeset accepts files whose sources changed in a local git
+// repository, making partial builds and validation of modified content
+// possible. Changes are determined relative to a base revision, and can
+// optionally include modified, staged and untracked files in the working tree.
+// Renamed files are tracked under their new paths, while deleted files are
+// ignored. The repository is inspected with the local "git" executable; no
+// network access is performed.
+//
+//	filter, err := changeset.New("content").Since("origin/main").Load()
+package changeset
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"foosoft.net/projects/goldsmith"
+	"foosoft.net/projects/goldsmith-components/filters/explain"
+)
+
+// Changeset filter context.
+type Changeset struct {
+	sourceDir   string
+	revision    string
+	workingTree bool
+
+	paths map[string]bool
+}
+
+// New creates a new instance of the Changeset filter for the provided source
+// directory, which must be located inside of a git working tree.
+func New(sourceDir string) *Changeset {
+	return &Changeset{
+		sourceDir:   sourceDir,
+		workingTree: true,
+		paths:       make(map[string]bool),
+	}
+}
+
+// Since sets the base revision that changes are determined relative to (default: "HEAD").
+func (self *Changeset) Since(revision string) *Changeset {
+	self.revision = revision
+	return self
+}
+
+// WorkingTree sets whether uncommitted changes in the working tree are included (default: true).
+func (self *Changeset) WorkingTree(workingTree bool) *Changeset {
+	self.workingTree = workingTree
+	return self
+}
+
+// Load queries the repository for changed files; it must be called before the filter is used.
+func (self *Changeset) Load() (*Changeset, error) {
+	revision := self.revision
+	if len(revision) == 0 {
+		if !self.workingTree {
+			return self, nil
+		}
+
+		revision = "HEAD"
+	}
+
+	args := []string{"diff", "--name-status", "-z", "-M", "--relative", "--no-ext-diff", revision}
+	if !self.workingTree {
+		args = append(args, "HEAD")
+	}
+
+	args = append(args, "--")
+
+	output, err := self.git(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := self.parseDiff(output); err != nil {
+		return nil, err
+	}
+
+	if self.workingTree {
+		output, err := self.git("ls-files", "-z", "--others", "--exclude-standard")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, path := range strings.Split(string(output), "\x00") {
+			if len(path) > 0 {
+				self.paths[path] = true
+			}
+		}
+	}
+
+	return self, nil
+}
+
+func (*Changeset) Name() string {
+	return "changeset"
+}
+
+func (self *Changeset) Accept(file *goldsmith.File) bool {
+	return self.paths[strings.TrimPrefix(file.Path(), "/")]
+}
+
+func (self *Changeset) Explain(file *goldsmith.File) *explain.Node {
+	node := &explain.Node{Filter: self.Name(), Accepted: self.Accept(file), Detail: "unchanged"}
+	if node.Accepted {
+		node.Detail = "changed"
+	}
+
+	return node
+}
+
+func (self *Changeset) parseDiff(output []byte) error {
+	fields := strings.Split(string(output), "\x00")
+	for i := 0; i < len(fields); i++ {
+		status := fields[i]
+		if len(status) == 0 {
+			continue
+		}
+
+		switch status[0] {
+		case 'R', 'C':
+			if i+2 >= len(fields) {
+				return errors.New("unexpected end of git diff output")
+			}
+
+			self.paths[fields[i+2]] = true
+			i += 2
+		case 'D':
+			i++
+		default:
+			if i+1 >= len(fields) {
+				return errors.New("unexpected end of git diff output")
+			}
+
+			self.paths[fields[i+1]] = true
+			i++
+		}
+	}
+
+	return nil
+}
+
+func (self *Changeset) git(args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("git", append([]string{"-C", self.sourceDir}, args...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); len(message) > 0 {
+			return nil, fmt.Errorf("git %s: %s", args[0], message)
+		}
+
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+
+	return stdout.Bytes(), nil
+}
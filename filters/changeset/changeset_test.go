@@ -0,0 +1,68 @@
+package changeset
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"foosoft.net/projects/goldsmith"
+	"foosoft.net/projects/goldsmith-components/harness"
+)
+
+func Test(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git executable not found")
+	}
+
+	repoDir := t.TempDir()
+
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", repoDir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+
+	write := func(path, data string) {
+		path = filepath.Join(repoDir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "-q")
+	write("edited.md", "Edited page.\n")
+	write("untouched.md", "Untouched page.\n")
+	write("moved.md", "A guide that was moved to a new location.\nIt has several lines of content,\nso that git can detect the rename.\n")
+	write("deleted.md", "A page that will be deleted.\n")
+	git("add", "-A")
+	git("commit", "-q", "-m", "base")
+	git("tag", "base")
+
+	write("edited.md", "Edited page, revised after the base revision.\n")
+	if err := os.Mkdir(filepath.Join(repoDir, "docs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	git("mv", "moved.md", "docs/moved.md")
+	git("rm", "-q", "deleted.md")
+	git("commit", "-q", "-a", "-m", "changes")
+	write("untracked.md", "A brand new page that has not been committed.\n")
+
+	filter, err := New(repoDir).Since("base").Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	harness.Validate(
+		t,
+		func(gs *goldsmith.Goldsmith) {
+			gs.FilterPush(filter)
+		},
+	)
+}
@@ -2,6 +2,7 @@ package condition
 
 import (
 	"foosoft.net/projects/goldsmith"
+	"foosoft.net/projects/goldsmith-components/filters/explain"
 )
 
 type Condition struct {
@@ -19,3 +20,7 @@ func (*Condition) Name() string {
 func (self *Condition) Accept(file *goldsmith.File) bool {
 	return self.accept
 }
+
+func (self *Condition) Explain(file *goldsmith.File) *explain.Node {
+	return &explain.Node{Filter: self.Name(), Accepted: self.accept}
+}
@@ -0,0 +1,140 @@
+// Package explain records why filters accept or reject files. Filters which
+// implement the Explainer interface describe their decisions, including the
+// decisions of any nested filters, as a tree of nodes that can be printed in a
+// readable form:
+//
+//	blog/draft.md: rejected
+//	└─ and: rejected
+//	   ├─ wildcard: accepted (matched "blog/**")
+//	   └─ not: rejected
+//	      └─ expression: accepted (Draft is true)
+//
+// The Explain filter wraps any other filter and writes the decision tree for
+// every file it evaluates, which is useful for finding out why a plugin skips
+// a file.
+package explain
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"foosoft.net/projects/goldsmith"
+)
+
+// Node describes the decision made by a single filter for a file.
+type Node struct {
+	Filter   string
+	Accepted bool
+	Detail   string
+	Err      error
+	Children []*Node
+}
+
+// Explainer is implemented by filters that can describe their decisions.
+type Explainer interface {
+	goldsmith.Filter
+	Explain(file *goldsmith.File) *Node
+}
+
+// Trace evaluates the filter for the provided file, returning the decision
+// tree. Filters that do not implement Explainer are represented by a single
+// node containing their name and decision.
+func Trace(filter goldsmith.Filter, file *goldsmith.File) *Node {
+	if explainer, ok := filter.(Explainer); ok {
+		return explainer.Explain(file)
+	}
+
+	return &Node{Filter: filter.Name(), Accepted: filter.Accept(file)}
+}
+
+// Errors returns all errors recorded in the decision tree.
+func (self *Node) Errors() []error {
+	var errs []error
+	if self.Err != nil {
+		errs = append(errs, self.Err)
+	}
+
+	for _, child := range self.Children {
+		errs = append(errs, child.Errors()...)
+	}
+
+	return errs
+}
+
+func (self *Node) String() string {
+	var builder strings.Builder
+	self.write(&builder, "", "")
+	return builder.String()
+}
+
+func (self *Node) describe() string {
+	result := "rejected"
+	if self.Accepted {
+		result = "accepted"
+	}
+
+	description := fmt.Sprintf("%s: %s", self.Filter, result)
+	if len(self.Detail) > 0 {
+		description += fmt.Sprintf(" (%s)", self.Detail)
+	}
+
+	if self.Err != nil {
+		description += fmt.Sprintf(" [error: %v]", self.Err)
+	}
+
+	return description
+}
+
+func (self *Node) write(builder *strings.Builder, prefix, childPrefix string) {
+	builder.WriteString(prefix)
+	builder.WriteString(self.describe())
+	builder.WriteString("\n")
+
+	for i, child := range self.Children {
+		if i == len(self.Children)-1 {
+			child.write(builder, childPrefix+"└─ ", childPrefix+"   ")
+		} else {
+			child.write(builder, childPrefix+"├─ ", childPrefix+"│  ")
+		}
+	}
+}
+
+// Explain filter context.
+type Explain struct {
+	filter goldsmith.Filter
+	writer io.Writer
+	mutex  sync.Mutex
+}
+
+// New creates a new instance of the Explain filter, which wraps the provided filter.
+func New(filter goldsmith.Filter) *Explain {
+	return &Explain{filter: filter, writer: os.Stderr}
+}
+
+// Writer sets the destination that decision trees are written to (default: os.Stderr).
+func (self *Explain) Writer(writer io.Writer) *Explain {
+	self.writer = writer
+	return self
+}
+
+func (self *Explain) Name() string {
+	return self.filter.Name()
+}
+
+func (self *Explain) Accept(file *goldsmith.File) bool {
+	return self.Explain(file).Accepted
+}
+
+func (self *Explain) Explain(file *goldsmith.File) *Node {
+	node := Trace(self.filter, file)
+	root := &Node{Filter: file.Path(), Accepted: node.Accepted, Children: []*Node{node}}
+
+	self.mutex.Lock()
+	io.WriteString(self.writer, root.String())
+	self.mutex.Unlock()
+
+	return root
+}
@@ -0,0 +1,74 @@
+package explain_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"foosoft.net/projects/goldsmith"
+	"foosoft.net/projects/goldsmith-components/filters/explain"
+	"foosoft.net/projects/goldsmith-components/filters/operator"
+	"foosoft.net/projects/goldsmith-components/filters/wildcard"
+	"foosoft.net/projects/goldsmith-components/harness"
+)
+
+func Test(t *testing.T) {
+	var buff bytes.Buffer
+
+	harness.Validate(
+		t,
+		func(gs *goldsmith.Goldsmith) {
+			gs.FilterPush(explain.New(operator.And(
+				wildcard.New("**/*.md"),
+				operator.Not(wildcard.New("drafts/**")),
+			)).Writer(&buff))
+		},
+	)
+
+	trees := []string{
+		"page.md: accepted\n" +
+			"└─ and: accepted\n" +
+			"   ├─ wildcard: accepted (matched \"**/*.md\")\n" +
+			"   └─ not: accepted\n" +
+			"      └─ wildcard: rejected (no match in [\"drafts/**\"])\n",
+		"notes.txt: rejected\n" +
+			"└─ and: rejected\n" +
+			"   └─ wildcard: rejected (no match in [\"**/*.md\"])\n",
+		"drafts/draft.md: rejected\n" +
+			"└─ and: rejected\n" +
+			"   ├─ wildcard: accepted (matched \"**/*.md\")\n" +
+			"   └─ not: rejected\n" +
+			"      └─ wildcard: accepted (matched \"drafts/**\")\n",
+	}
+
+	for _, tree := range trees {
+		if !strings.Contains(buff.String(), tree) {
+			t.Errorf("missing decision tree:\n%s", tree)
+		}
+	}
+}
+
+func TestNested(t *testing.T) {
+	var buff bytes.Buffer
+
+	harness.Validate(
+		t,
+		func(gs *goldsmith.Goldsmith) {
+			inner := explain.New(operator.And(
+				wildcard.New("**/*.md"),
+				operator.Not(wildcard.New("drafts/**")),
+			)).Writer(io.Discard)
+
+			gs.FilterPush(explain.New(inner).Writer(&buff))
+		},
+	)
+
+	tree := "page.md: accepted\n" +
+		"└─ page.md: accepted\n" +
+		"   └─ and: accepted\n"
+
+	if !strings.Contains(buff.String(), tree) {
+		t.Errorf("missing decision tree:\n%s", tree)
+	}
+}
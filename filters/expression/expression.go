@@ -0,0 +1,431 @@
+// Package expression builds filters from a small expression language, making
+// it possible to describe filter trees in configuration files instead of
+// composing them from Go code.
+//
+//	path ~ "blog/**" && !Draft && Date <= now()
+//
+// Identifiers starting with a lowercase letter refer to file attributes
+// ("path", "name", "dir" and "ext"), while all other identifiers refer to file
+// properties. A bare identifier is true if the property exists and is not
+// false, zero or empty. Values can be compared with the "==", "!=", "<", "<=",
+// ">" and ">=" operators, and matched against glob patterns with "~" and "!~".
+// Sub-expressions are combined with "&&", "||" and "!", and can be grouped with
+// parentheses. The functions "now()" and "date(string)" produce time values,
+// and strings are compared with times by parsing them as dates in the same
+// layouts as the "schedule" filter and the "frontmatter" plugin.
+//
+// Where possible, expressions compile to the existing "wildcard", "operator"
+// and "condition" filters; "path ~ pattern" becomes a wildcard filter, logical
+// operators become operator filters and literal booleans become conditions.
+package expression
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"foosoft.net/projects/goldsmith"
+	"foosoft.net/projects/goldsmith-components/filters/explain"
+	"foosoft.net/projects/goldsmith-components/internal/date"
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// SyntaxError describes a problem found while parsing an expression.
+type SyntaxError struct {
+	Column  int
+	Message string
+}
+
+func (self *SyntaxError) Error() string {
+	return fmt.Sprintf("column %d: %s", self.Column, self.Message)
+}
+
+// Expression filter context.
+type Expression struct {
+	source string
+	filter goldsmith.Filter
+}
+
+// New parses the provided source into a new instance of the Expression filter.
+func New(source string) (*Expression, error) {
+	filter, err := parse(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Expression{source, filter}, nil
+}
+
+// MustNew is like New but panics if the source cannot be parsed.
+func MustNew(source string) *Expression {
+	expression, err := New(source)
+	if err != nil {
+		panic(fmt.Sprintf("expression: %q: %v", source, err))
+	}
+
+	return expression
+}
+
+// Source returns the source the expression was parsed from.
+func (self *Expression) Source() string {
+	return self.source
+}
+
+// Filter returns the filter tree the expression was compiled to.
+func (self *Expression) Filter() goldsmith.Filter {
+	return self.filter
+}
+
+func (*Expression) Name() string {
+	return "expression"
+}
+
+func (self *Expression) Accept(file *goldsmith.File) bool {
+	return self.filter.Accept(file)
+}
+
+func (self *Expression) Explain(file *goldsmith.File) *explain.Node {
+	child := explain.Trace(self.filter, file)
+	return &explain.Node{
+		Filter:   self.Name(),
+		Accepted: child.Accepted,
+		Detail:   self.source,
+		Children: []*explain.Node{child},
+	}
+}
+
+type operand interface {
+	value(file *goldsmith.File) (interface{}, bool)
+	describe() string
+}
+
+func describeValue(operand operand, file *goldsmith.File) string {
+	value, ok := operand.value(file)
+	if !ok {
+		return "missing"
+	}
+
+	if str, ok := value.(string); ok {
+		return strconv.Quote(str)
+	}
+
+	return fmt.Sprint(value)
+}
+
+type operandAttribute struct {
+	name string
+}
+
+func (self *operandAttribute) value(file *goldsmith.File) (interface{}, bool) {
+	switch self.name {
+	case "path":
+		return file.Path(), true
+	case "name":
+		return file.Name(), true
+	case "dir":
+		return file.Dir(), true
+	case "ext":
+		return file.Ext(), true
+	}
+
+	return nil, false
+}
+
+func (self *operandAttribute) describe() string {
+	return self.name
+}
+
+type operandProp struct {
+	name string
+}
+
+func (self *operandProp) value(file *goldsmith.File) (interface{}, bool) {
+	value, ok := file.Prop(self.name)
+	return value, ok && value != nil
+}
+
+func (self *operandProp) describe() string {
+	return self.name
+}
+
+type operandLiteral struct {
+	literal interface{}
+}
+
+func (self *operandLiteral) value(*goldsmith.File) (interface{}, bool) {
+	return self.literal, true
+}
+
+func (self *operandLiteral) describe() string {
+	if str, ok := self.literal.(string); ok {
+		return strconv.Quote(str)
+	}
+
+	return fmt.Sprint(self.literal)
+}
+
+type operandNow struct{}
+
+func (*operandNow) value(*goldsmith.File) (interface{}, bool) {
+	return time.Now(), true
+}
+
+func (*operandNow) describe() string {
+	return "now()"
+}
+
+type filterTruth struct {
+	operand operand
+}
+
+func (*filterTruth) Name() string {
+	return "expression"
+}
+
+func (self *filterTruth) Accept(file *goldsmith.File) bool {
+	value, ok := self.operand.value(file)
+	if !ok {
+		return false
+	}
+
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		return len(v) > 0
+	case time.Time:
+		return !v.IsZero()
+	case []interface{}:
+		return len(v) > 0
+	case map[string]interface{}:
+		return len(v) > 0
+	case map[interface{}]interface{}:
+		return len(v) > 0
+	}
+
+	if number, ok := toNumber(value); ok {
+		return number != 0
+	}
+
+	return true
+}
+
+func (self *filterTruth) Explain(file *goldsmith.File) *explain.Node {
+	return &explain.Node{
+		Filter:   self.Name(),
+		Accepted: self.Accept(file),
+		Detail:   fmt.Sprintf("%s is %s", self.operand.describe(), describeValue(self.operand, file)),
+	}
+}
+
+type filterCompare struct {
+	left  operand
+	op    string
+	right operand
+}
+
+func (*filterCompare) Name() string {
+	return "expression"
+}
+
+func (self *filterCompare) Accept(file *goldsmith.File) bool {
+	left, ok := self.left.value(file)
+	if !ok {
+		return false
+	}
+
+	right, ok := self.right.value(file)
+	if !ok {
+		return false
+	}
+
+	order, ok := compare(left, right)
+	if !ok {
+		return false
+	}
+
+	switch self.op {
+	case "==":
+		return order == 0
+	case "!=":
+		return order != 0
+	}
+
+	if _, ok := left.(bool); ok {
+		return false
+	}
+
+	switch self.op {
+	case "<":
+		return order < 0
+	case "<=":
+		return order <= 0
+	case ">":
+		return order > 0
+	case ">=":
+		return order >= 0
+	}
+
+	return false
+}
+
+func (self *filterCompare) Explain(file *goldsmith.File) *explain.Node {
+	return &explain.Node{
+		Filter:   self.Name(),
+		Accepted: self.Accept(file),
+		Detail: fmt.Sprintf(
+			"%s %s %s with %s and %s",
+			self.left.describe(),
+			self.op,
+			self.right.describe(),
+			describeValue(self.left, file),
+			describeValue(self.right, file),
+		),
+	}
+}
+
+type filterGlob struct {
+	operand operand
+	pattern string
+}
+
+func (*filterGlob) Name() string {
+	return "expression"
+}
+
+func (self *filterGlob) Accept(file *goldsmith.File) bool {
+	value, ok := self.operand.value(file)
+	if !ok {
+		return false
+	}
+
+	str, ok := value.(string)
+	if !ok {
+		return false
+	}
+
+	matched, _ := doublestar.Match(strings.ToLower(self.pattern), strings.ToLower(str))
+	return matched
+}
+
+func (self *filterGlob) Explain(file *goldsmith.File) *explain.Node {
+	return &explain.Node{
+		Filter:   self.Name(),
+		Accepted: self.Accept(file),
+		Detail:   fmt.Sprintf("%s ~ %q with %s", self.operand.describe(), self.pattern, describeValue(self.operand, file)),
+	}
+}
+
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+
+	return 0, false
+}
+
+func compare(left, right interface{}) (int, bool) {
+	if leftTime, ok := left.(time.Time); ok {
+		if rightStr, ok := right.(string); ok {
+			parsed, err := date.Parse(rightStr)
+			if err != nil {
+				return 0, false
+			}
+
+			right = parsed
+		}
+
+		rightTime, ok := right.(time.Time)
+		if !ok {
+			return 0, false
+		}
+
+		switch {
+		case leftTime.Before(rightTime):
+			return -1, true
+		case leftTime.After(rightTime):
+			return 1, true
+		default:
+			return 0, true
+		}
+	}
+
+	if _, ok := right.(time.Time); ok {
+		if _, ok := left.(string); ok {
+			order, ok := compare(right, left)
+			return -order, ok
+		}
+
+		return 0, false
+	}
+
+	if leftNumber, ok := toNumber(left); ok {
+		rightNumber, ok := toNumber(right)
+		if !ok {
+			rightStr, isStr := right.(string)
+			if !isStr {
+				return 0, false
+			}
+
+			var err error
+			if rightNumber, err = strconv.ParseFloat(rightStr, 64); err != nil {
+				return 0, false
+			}
+		}
+
+		switch {
+		case leftNumber < rightNumber:
+			return -1, true
+		case leftNumber > rightNumber:
+			return 1, true
+		default:
+			return 0, true
+		}
+	}
+
+	if _, ok := toNumber(right); ok {
+		order, ok := compare(right, left)
+		return -order, ok
+	}
+
+	switch leftValue := left.(type) {
+	case string:
+		if rightValue, ok := right.(string); ok {
+			return strings.Compare(leftValue, rightValue), true
+		}
+	case bool:
+		if rightValue, ok := right.(bool); ok {
+			if leftValue == rightValue {
+				return 0, true
+			}
+
+			return 1, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,59 @@
+package expression
+
+import (
+	"testing"
+
+	"foosoft.net/projects/goldsmith"
+	"foosoft.net/projects/goldsmith-components/harness"
+	"foosoft.net/projects/goldsmith-components/plugins/frontmatter"
+)
+
+func TestPath(t *testing.T) {
+	harness.ValidateCase(
+		t,
+		"path",
+		func(gs *goldsmith.Goldsmith) {
+			gs.FilterPush(MustNew(`path ~ "blog/**" && ext != ".txt"`))
+		},
+	)
+}
+
+func TestProps(t *testing.T) {
+	harness.ValidateCase(
+		t,
+		"props",
+		func(gs *goldsmith.Goldsmith) {
+			gs.
+				Chain(frontmatter.New()).
+				FilterPush(MustNew(`!Draft && Date <= now() && (Weight < 10 || Title == "Heavy post")`))
+		},
+	)
+}
+
+func TestErrors(t *testing.T) {
+	cases := []struct {
+		source string
+		column int
+	}{
+		{`path ~ "blog/**" &&`, 20},
+		{`path ~ blog`, 8},
+		{`!Draft && (Date <= now()`, 25},
+		{`Title == "unterminated`, 10},
+		{`Date <= later()`, 9},
+		{`Draft # true`, 7},
+		{`size > 10`, 1},
+		{`path ~ "blog/[a-"`, 8},
+	}
+
+	for _, c := range cases {
+		_, err := New(c.source)
+		if err == nil {
+			t.Errorf("expected error for %q", c.source)
+			continue
+		}
+
+		if syntaxErr, ok := err.(*SyntaxError); !ok || syntaxErr.Column != c.column {
+			t.Errorf("unexpected error for %q: %v", c.source, err)
+		}
+	}
+}
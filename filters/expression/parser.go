@@ -0,0 +1,390 @@
+package expression
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"foosoft.net/projects/goldsmith"
+	"foosoft.net/projects/goldsmith-components/filters/condition"
+	"foosoft.net/projects/goldsmith-components/filters/operator"
+	"foosoft.net/projects/goldsmith-components/filters/wildcard"
+	"foosoft.net/projects/goldsmith-components/internal/date"
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenSymbol
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	column int
+}
+
+func (self token) describe() string {
+	switch self.kind {
+	case tokenEnd:
+		return "end of expression"
+	case tokenString:
+		return strconv.Quote(self.text)
+	default:
+		return fmt.Sprintf("%q", self.text)
+	}
+}
+
+func tokenize(source string) ([]token, error) {
+	var (
+		tokens []token
+		runes  = []rune(source)
+	)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		column := i + 1
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+
+			tokens = append(tokens, token{tokenIdent, string(runes[start:i]), column})
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			for i++; i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.'); i++ {
+			}
+
+			tokens = append(tokens, token{tokenNumber, string(runes[start:i]), column})
+		case r == '"' || r == '\'':
+			var builder strings.Builder
+			for i++; ; i++ {
+				if i >= len(runes) {
+					return nil, &SyntaxError{column, "unterminated string"}
+				}
+
+				if runes[i] == r {
+					i++
+					break
+				}
+
+				if runes[i] == '\\' {
+					if i++; i >= len(runes) {
+						return nil, &SyntaxError{column, "unterminated string"}
+					}
+
+					switch runes[i] {
+					case 'n':
+						builder.WriteRune('\n')
+					case 't':
+						builder.WriteRune('\t')
+					default:
+						builder.WriteRune(runes[i])
+					}
+				} else {
+					builder.WriteRune(runes[i])
+				}
+			}
+
+			tokens = append(tokens, token{tokenString, builder.String(), column})
+		default:
+			var symbol string
+			for _, candidate := range []string{"&&", "||", "==", "!=", "<=", ">=", "!~", "!", "<", ">", "~", "(", ")", ","} {
+				if strings.HasPrefix(string(runes[i:]), candidate) {
+					symbol = candidate
+					break
+				}
+			}
+
+			if len(symbol) == 0 {
+				return nil, &SyntaxError{column, fmt.Sprintf("unexpected character %q", r)}
+			}
+
+			tokens = append(tokens, token{tokenSymbol, symbol, column})
+			i += len(symbol)
+		}
+	}
+
+	tokens = append(tokens, token{tokenEnd, "", len(runes) + 1})
+	return tokens, nil
+}
+
+type parser struct {
+	tokens []token
+	index  int
+}
+
+func parse(source string) (goldsmith.Filter, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	self := &parser{tokens: tokens}
+	filter, err := self.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if next := self.peek(); next.kind != tokenEnd {
+		return nil, self.unexpected(next)
+	}
+
+	return filter, nil
+}
+
+func (self *parser) peek() token {
+	return self.tokens[self.index]
+}
+
+func (self *parser) next() token {
+	token := self.tokens[self.index]
+	if token.kind != tokenEnd {
+		self.index++
+	}
+
+	return token
+}
+
+func (self *parser) accept(symbol string) bool {
+	if next := self.peek(); next.kind == tokenSymbol && next.text == symbol {
+		self.index++
+		return true
+	}
+
+	return false
+}
+
+func (self *parser) expect(symbol string) error {
+	if !self.accept(symbol) {
+		next := self.peek()
+		return &SyntaxError{next.column, fmt.Sprintf("expected %q, found %s", symbol, next.describe())}
+	}
+
+	return nil
+}
+
+func (self *parser) unexpected(token token) error {
+	return &SyntaxError{token.column, fmt.Sprintf("unexpected %s", token.describe())}
+}
+
+func (self *parser) parseOr() (goldsmith.Filter, error) {
+	filter, err := self.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	filters := []goldsmith.Filter{filter}
+	for self.accept("||") {
+		filter, err := self.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, filter)
+	}
+
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+
+	return operator.Or(filters...), nil
+}
+
+func (self *parser) parseAnd() (goldsmith.Filter, error) {
+	filter, err := self.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	filters := []goldsmith.Filter{filter}
+	for self.accept("&&") {
+		filter, err := self.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, filter)
+	}
+
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+
+	return operator.And(filters...), nil
+}
+
+func (self *parser) parseUnary() (goldsmith.Filter, error) {
+	if self.accept("!") {
+		filter, err := self.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return operator.Not(filter), nil
+	}
+
+	if self.accept("(") {
+		filter, err := self.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := self.expect(")"); err != nil {
+			return nil, err
+		}
+
+		return filter, nil
+	}
+
+	return self.parseComparison()
+}
+
+func (self *parser) parseComparison() (goldsmith.Filter, error) {
+	leftToken := self.peek()
+	left, err := self.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	next := self.peek()
+	if next.kind != tokenSymbol {
+		return self.truth(left), nil
+	}
+
+	switch next.text {
+	case "~", "!~":
+		self.next()
+		patternToken := self.next()
+		if patternToken.kind != tokenString {
+			return nil, &SyntaxError{patternToken.column, fmt.Sprintf("expected pattern string, found %s", patternToken.describe())}
+		}
+
+		if !doublestar.ValidatePattern(patternToken.text) {
+			return nil, &SyntaxError{patternToken.column, fmt.Sprintf("malformed pattern %s", patternToken.describe())}
+		}
+
+		var filter goldsmith.Filter
+		if attribute, ok := left.(*operandAttribute); ok && attribute.name == "path" {
+			filter = wildcard.New(patternToken.text)
+		} else if _, ok := left.(*operandLiteral); ok {
+			return nil, &SyntaxError{leftToken.column, "pattern matching requires an attribute or property"}
+		} else {
+			filter = &filterGlob{left, patternToken.text}
+		}
+
+		if next.text == "!~" {
+			filter = operator.Not(filter)
+		}
+
+		return filter, nil
+	case "==", "!=", "<", "<=", ">", ">=":
+		self.next()
+		right, err := self.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+
+		return &filterCompare{left, next.text, right}, nil
+	}
+
+	return self.truth(left), nil
+}
+
+func (self *parser) truth(operand operand) goldsmith.Filter {
+	if literal, ok := operand.(*operandLiteral); ok {
+		if value, ok := literal.literal.(bool); ok {
+			return condition.New(value)
+		}
+	}
+
+	return &filterTruth{operand}
+}
+
+func (self *parser) parseOperand() (operand, error) {
+	token := self.next()
+
+	switch token.kind {
+	case tokenString:
+		return &operandLiteral{token.text}, nil
+	case tokenNumber:
+		number, err := strconv.ParseFloat(token.text, 64)
+		if err != nil {
+			return nil, &SyntaxError{token.column, fmt.Sprintf("invalid number %q", token.text)}
+		}
+
+		return &operandLiteral{number}, nil
+	case tokenIdent:
+		if self.accept("(") {
+			return self.parseCall(token)
+		}
+
+		switch token.text {
+		case "true":
+			return &operandLiteral{true}, nil
+		case "false":
+			return &operandLiteral{false}, nil
+		case "path", "name", "dir", "ext":
+			return &operandAttribute{token.text}, nil
+		}
+
+		if unicode.IsLower([]rune(token.text)[0]) {
+			return nil, &SyntaxError{token.column, fmt.Sprintf("unknown attribute %q", token.text)}
+		}
+
+		return &operandProp{token.text}, nil
+	}
+
+	return nil, self.unexpected(token)
+}
+
+func (self *parser) parseCall(name token) (operand, error) {
+	var args []token
+	for !self.accept(")") {
+		if len(args) > 0 {
+			if err := self.expect(","); err != nil {
+				return nil, err
+			}
+		}
+
+		arg := self.next()
+		if arg.kind != tokenString && arg.kind != tokenNumber {
+			return nil, &SyntaxError{arg.column, fmt.Sprintf("expected literal argument, found %s", arg.describe())}
+		}
+
+		args = append(args, arg)
+	}
+
+	switch name.text {
+	case "now":
+		if len(args) != 0 {
+			return nil, &SyntaxError{name.column, "now() takes no arguments"}
+		}
+
+		return &operandNow{}, nil
+	case "date":
+		if len(args) != 1 || args[0].kind != tokenString {
+			return nil, &SyntaxError{name.column, "date() takes a single string argument"}
+		}
+
+		parsed, err := date.Parse(args[0].text)
+		if err != nil {
+			return nil, &SyntaxError{args[0].column, fmt.Sprintf("invalid date %q", args[0].text)}
+		}
+
+		return &operandLiteral{parsed}, nil
+	}
+
+	return nil, &SyntaxError{name.column, fmt.Sprintf("unknown function %q", name.text)}
+}
@@ -0,0 +1,300 @@
+// Package ignore accepts files using the pattern semantics of ".gitignore"
+// files. Patterns passed to New form an ordered include list, in which later
+// patterns override earlier ones and patterns prefixed with "!" exclude
+// previously included files:
+//
+//	ignore.New("**/*.md", "!drafts/**")
+//
+// Ignore files such as ".gitignore" or ".goldsmithignore" can additionally be
+// loaded from every directory of the source tree; files ignored by them are
+// rejected regardless of the include list. As with git, rules in deeper
+// directories take precedence over rules in their parents, and a file cannot
+// be re-included if one of its parent directories is excluded.
+//
+// Patterns support negation ("!"), comments ("#"), directory-only matches
+// (trailing "/"), anchoring (leading or inner "/") and "**" wildcards.
+package ignore
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"foosoft.net/projects/goldsmith"
+	"foosoft.net/projects/goldsmith-components/filters/explain"
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+type pattern struct {
+	line     string
+	glob     string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+func parsePattern(line string) *pattern {
+	line = strings.TrimRight(line, "\r")
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+
+	if len(line) == 0 || strings.HasPrefix(line, "#") {
+		return nil
+	}
+
+	self := pattern{line: line}
+	if strings.HasPrefix(line, "!") {
+		self.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, "\\!") || strings.HasPrefix(line, "\\#") {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		self.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+
+	if strings.HasPrefix(line, "/") {
+		self.anchored = true
+		line = strings.TrimLeft(line, "/")
+	} else if strings.Contains(line, "/") {
+		self.anchored = true
+	}
+
+	if len(line) == 0 {
+		return nil
+	}
+
+	self.glob = line
+	return &self
+}
+
+func (self *pattern) match(relPath string, isDir bool) bool {
+	if self.dirOnly && !isDir {
+		return false
+	}
+
+	if self.anchored {
+		// As with git, "dir/**" matches everything inside of the directory,
+		// but not the directory itself.
+		if dir := strings.TrimSuffix(self.glob, "/**"); dir != self.glob {
+			if matched, _ := doublestar.Match(dir, relPath); matched {
+				return false
+			}
+		}
+
+		matched, _ := doublestar.Match(self.glob, relPath)
+		return matched
+	}
+
+	matched, _ := doublestar.Match(self.glob, path.Base(relPath))
+	return matched
+}
+
+type ruleSet struct {
+	source   string
+	baseDir  string
+	patterns []*pattern
+}
+
+func newRuleSet(source, baseDir string, lines []string) *ruleSet {
+	self := &ruleSet{source: source, baseDir: baseDir}
+	for _, line := range lines {
+		if pattern := parsePattern(line); pattern != nil {
+			self.patterns = append(self.patterns, pattern)
+		}
+	}
+
+	return self
+}
+
+func (self *ruleSet) match(filePath string, isDir bool) (matched bool, decider *pattern) {
+	relPath := filePath
+	if len(self.baseDir) > 0 {
+		if !strings.HasPrefix(filePath, self.baseDir+"/") {
+			return false, nil
+		}
+
+		relPath = filePath[len(self.baseDir)+1:]
+	}
+
+	for _, pattern := range self.patterns {
+		if pattern.match(relPath, isDir) {
+			matched = !pattern.negate
+			decider = pattern
+		}
+	}
+
+	return
+}
+
+func matchRuleSets(ruleSets []*ruleSet, filePath string) (bool, string) {
+	var reason string
+
+	parts := strings.Split(filePath, "/")
+	for i := 1; i <= len(parts); i++ {
+		var (
+			prefix  = strings.Join(parts[:i], "/")
+			isDir   = i < len(parts)
+			matched bool
+		)
+
+		for _, ruleSet := range ruleSets {
+			if m, decider := ruleSet.match(prefix, isDir); decider != nil {
+				matched = m
+				reason = fmt.Sprintf("%q in %s matched %q", decider.line, ruleSet.source, prefix)
+			}
+		}
+
+		if matched {
+			return true, reason
+		}
+	}
+
+	return false, reason
+}
+
+// Ignore filter context.
+type Ignore struct {
+	include   []*ruleSet
+	ignore    []*ruleSet
+	filenames []string
+}
+
+// New creates a new instance of the Ignore filter. The provided patterns form
+// an ordered include list; if no patterns are provided, all files are included.
+func New(patterns ...string) *Ignore {
+	self := new(Ignore)
+	if len(patterns) > 0 {
+		self.include = []*ruleSet{newRuleSet("include list", "", patterns)}
+	}
+
+	return self
+}
+
+// Load reads ignore files with the provided names (default: ".gitignore" and
+// ".goldsmithignore") from every directory under the source directory. Files
+// matched by these are rejected, as are the ignore files themselves.
+func (self *Ignore) Load(sourceDir string, filenames ...string) (*Ignore, error) {
+	if len(filenames) == 0 {
+		filenames = []string{".gitignore", ".goldsmithignore"}
+	}
+
+	var ruleSets []*ruleSet
+	err := filepath.Walk(sourceDir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() || !containsString(filenames, info.Name()) {
+			return nil
+		}
+
+		relDir, err := filepath.Rel(sourceDir, filepath.Dir(filePath))
+		if err != nil {
+			return err
+		}
+
+		lines, err := readLines(filePath)
+		if err != nil {
+			return err
+		}
+
+		baseDir := filepath.ToSlash(relDir)
+		if baseDir == "." {
+			baseDir = ""
+		}
+
+		ruleSets = append(ruleSets, newRuleSet(path.Join(baseDir, info.Name()), baseDir, lines))
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(ruleSets, func(i, j int) bool {
+		return depth(ruleSets[i].baseDir) < depth(ruleSets[j].baseDir)
+	})
+
+	self.ignore = append(self.ignore, ruleSets...)
+	self.filenames = append(self.filenames, filenames...)
+	return self, nil
+}
+
+func (*Ignore) Name() string {
+	return "ignore"
+}
+
+func (self *Ignore) Accept(file *goldsmith.File) bool {
+	accept, _ := self.check(file)
+	return accept
+}
+
+func (self *Ignore) Explain(file *goldsmith.File) *explain.Node {
+	accept, reason := self.check(file)
+	return &explain.Node{Filter: self.Name(), Accepted: accept, Detail: reason}
+}
+
+func (self *Ignore) check(file *goldsmith.File) (bool, string) {
+	if containsString(self.filenames, file.Name()) {
+		return false, "ignore file"
+	}
+
+	filePath := strings.TrimPrefix(file.Path(), "/")
+	if len(self.include) > 0 {
+		if included, reason := matchRuleSets(self.include, filePath); !included {
+			if len(reason) == 0 {
+				reason = "not in include list"
+			}
+
+			return false, reason
+		}
+	}
+
+	if ignored, reason := matchRuleSets(self.ignore, filePath); ignored {
+		return false, reason
+	}
+
+	return true, ""
+}
+
+func readLines(filePath string) ([]string, error) {
+	fp, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(fp)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	return lines, scanner.Err()
+}
+
+func containsString(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+
+	return false
+}
+
+func depth(dir string) int {
+	if len(dir) == 0 {
+		return 0
+	}
+
+	return strings.Count(dir, "/") + 1
+}
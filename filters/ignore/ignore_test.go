@@ -0,0 +1,37 @@
+package ignore
+
+import (
+	"testing"
+
+	"foosoft.net/projects/goldsmith"
+	"foosoft.net/projects/goldsmith-components/harness"
+)
+
+func Test(t *testing.T) {
+	filter, err := New("**/*.md", "**/*.tmp", "!drafts/**").Load("testdata/source", ".goldsmithignore")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	harness.Validate(
+		t,
+		func(gs *goldsmith.Goldsmith) {
+			gs.FilterPush(filter)
+		},
+	)
+}
+
+func TestNegation(t *testing.T) {
+	filter, err := New("docs/**", "build/**", "!docs/secret.md").Load("testdata/negation/source", ".gitignore")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	harness.ValidateCase(
+		t,
+		"negation",
+		func(gs *goldsmith.Goldsmith) {
+			gs.FilterPush(filter)
+		},
+	)
+}
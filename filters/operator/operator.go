@@ -2,6 +2,7 @@ package operator
 
 import (
 	"foosoft.net/projects/goldsmith"
+	"foosoft.net/projects/goldsmith-components/filters/explain"
 )
 
 type Operator interface {
@@ -17,7 +18,7 @@ type operatorAnd struct {
 }
 
 func (*operatorAnd) Name() string {
-	return "operator"
+	return "and"
 }
 
 func (self *operatorAnd) Accept(file *goldsmith.File) bool {
@@ -30,6 +31,20 @@ func (self *operatorAnd) Accept(file *goldsmith.File) bool {
 	return true
 }
 
+func (self *operatorAnd) Explain(file *goldsmith.File) *explain.Node {
+	node := &explain.Node{Filter: self.Name(), Accepted: true}
+	for _, filter := range self.filters {
+		child := explain.Trace(filter, file)
+		node.Children = append(node.Children, child)
+		if !child.Accepted {
+			node.Accepted = false
+			break
+		}
+	}
+
+	return node
+}
+
 func Not(self goldsmith.Filter) Operator {
 	return &operatorNot{self}
 }
@@ -39,13 +54,18 @@ type operatorNot struct {
 }
 
 func (*operatorNot) Name() string {
-	return "operator"
+	return "not"
 }
 
 func (self *operatorNot) Accept(file *goldsmith.File) bool {
 	return !self.filter.Accept(file)
 }
 
+func (self *operatorNot) Explain(file *goldsmith.File) *explain.Node {
+	child := explain.Trace(self.filter, file)
+	return &explain.Node{Filter: self.Name(), Accepted: !child.Accepted, Children: []*explain.Node{child}}
+}
+
 func Or(self ...goldsmith.Filter) Operator {
 	return &operatorOr{self}
 }
@@ -55,7 +75,7 @@ type operatorOr struct {
 }
 
 func (*operatorOr) Name() string {
-	return "operator"
+	return "or"
 }
 
 func (self *operatorOr) Accept(file *goldsmith.File) bool {
@@ -67,3 +87,17 @@ func (self *operatorOr) Accept(file *goldsmith.File) bool {
 
 	return false
 }
+
+func (self *operatorOr) Explain(file *goldsmith.File) *explain.Node {
+	node := &explain.Node{Filter: self.Name()}
+	for _, filter := range self.filters {
+		child := explain.Trace(filter, file)
+		node.Children = append(node.Children, child)
+		if child.Accepted {
+			node.Accepted = true
+			break
+		}
+	}
+
+	return node
+}
@@ -0,0 +1,135 @@
+// Package regex accepts files whose paths match regular expressions. Unlike
+// the "wildcard" filter, named capture groups can be written into the props of
+// matching files, making values encoded in file paths available to plugins
+// further down the chain:
+//
+//	regex.MustNew(`(?P<Year>\d{4})/(?P<Slug>[^/]+)\.md$`).CaptureProps(true)
+//
+// Patterns use the syntax of the standard "regexp" package and are not
+// implicitly anchored; use "^" and "$" to match complete paths.
+package regex
+
+import (
+	"fmt"
+	"regexp"
+
+	"foosoft.net/projects/goldsmith"
+	"foosoft.net/projects/goldsmith-components/filters/explain"
+)
+
+// Regex filter context.
+type Regex struct {
+	sources       []string
+	patterns      []*regexp.Regexp
+	captureProps  bool
+	propPrefix    string
+	caseSensitive bool
+}
+
+// New creates a new instance of the Regex filter, compiling the provided patterns.
+func New(patterns ...string) (*Regex, error) {
+	self := &Regex{sources: patterns, caseSensitive: true}
+	if err := self.compile(); err != nil {
+		return nil, err
+	}
+
+	return self, nil
+}
+
+// MustNew is like New but panics if any of the patterns cannot be compiled.
+func MustNew(patterns ...string) *Regex {
+	self, err := New(patterns...)
+	if err != nil {
+		panic(fmt.Sprintf("regex: %v", err))
+	}
+
+	return self
+}
+
+// CaseSensitive sets whether patterns are matched case sensitively (default: true).
+func (self *Regex) CaseSensitive(caseSensitive bool) *Regex {
+	if self.caseSensitive != caseSensitive {
+		self.caseSensitive = caseSensitive
+		if err := self.compile(); err != nil {
+			panic(fmt.Sprintf("regex: %v", err))
+		}
+	}
+
+	return self
+}
+
+// CaptureProps sets whether named capture groups are stored as props of matching files (default: false).
+func (self *Regex) CaptureProps(captureProps bool) *Regex {
+	self.captureProps = captureProps
+	return self
+}
+
+// PropPrefix sets the string prepended to capture group names when storing them as props (default: "").
+func (self *Regex) PropPrefix(propPrefix string) *Regex {
+	self.propPrefix = propPrefix
+	return self
+}
+
+func (*Regex) Name() string {
+	return "regex"
+}
+
+func (self *Regex) Accept(file *goldsmith.File) bool {
+	return self.match(file) != nil
+}
+
+func (self *Regex) Explain(file *goldsmith.File) *explain.Node {
+	node := &explain.Node{Filter: self.Name()}
+	if pattern := self.match(file); pattern != nil {
+		node.Accepted = true
+		node.Detail = fmt.Sprintf("matched %q", pattern.String())
+	} else {
+		node.Detail = fmt.Sprintf("no match in %q", self.sources)
+	}
+
+	return node
+}
+
+func (self *Regex) match(file *goldsmith.File) *regexp.Regexp {
+	filePath := file.Path()
+
+	for _, pattern := range self.patterns {
+		match := pattern.FindStringSubmatchIndex(filePath)
+		if match == nil {
+			continue
+		}
+
+		if self.captureProps {
+			for i, name := range pattern.SubexpNames() {
+				if len(name) == 0 || match[i*2] < 0 {
+					continue
+				}
+
+				file.SetProp(self.propPrefix+name, filePath[match[i*2]:match[i*2+1]])
+			}
+		}
+
+		return pattern
+	}
+
+	return nil
+}
+
+func (self *Regex) compile() error {
+	var patterns []*regexp.Regexp
+	for _, source := range self.sources {
+		if !self.caseSensitive {
+			source = "(?i)" + source
+		}
+
+		pattern, err := regexp.Compile(source)
+		if err != nil {
+			return err
+		}
+
+		patterns = append(patterns, pattern)
+	}
+
+	self.patterns = patterns
+	return nil
+}
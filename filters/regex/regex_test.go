@@ -0,0 +1,27 @@
+package regex
+
+import (
+	"testing"
+
+	"foosoft.net/projects/goldsmith"
+	"foosoft.net/projects/goldsmith-components/filters/operator"
+	"foosoft.net/projects/goldsmith-components/filters/wildcard"
+	"foosoft.net/projects/goldsmith-components/harness"
+	"foosoft.net/projects/goldsmith-components/plugins/frontmatter"
+	"foosoft.net/projects/goldsmith-components/plugins/layout"
+)
+
+func Test(t *testing.T) {
+	harness.Validate(
+		t,
+		func(gs *goldsmith.Goldsmith) {
+			gs.
+				FilterPush(operator.Or(
+					MustNew(`(?P<Year>\d{4})/(?P<Slug>[^/]+)\.html$`).CaptureProps(true),
+					wildcard.New("*.gohtml"),
+				)).
+				Chain(frontmatter.New()).
+				Chain(layout.New())
+		},
+	)
+}
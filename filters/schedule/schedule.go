@@ -0,0 +1,157 @@
+// Package schedule accepts files based on their publication and expiry dates,
+// making it possible to schedule content ahead of time. Files with a
+// publication date after the reference time, or an expiry date at or before the
+// reference time, are rejected. Files without dates are always accepted.
+//
+// Dates can be stored in props as time.Time values or as strings in common
+// layouts such as "2006-01-02", "2006-01-02 15:04" or RFC 3339.
+package schedule
+
+import (
+	"fmt"
+	"time"
+
+	"foosoft.net/projects/goldsmith"
+	"foosoft.net/projects/goldsmith-components/filters/explain"
+	"foosoft.net/projects/goldsmith-components/internal/date"
+)
+
+// ParseDate converts a prop value into a time. Supported values are time.Time
+// and strings in one of the recognized layouts; dates without an explicit
+// time zone are interpreted in the local time zone.
+func ParseDate(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case *time.Time:
+		if v != nil {
+			return *v, nil
+		}
+	case string:
+		return date.Parse(v)
+	}
+
+	return time.Time{}, fmt.Errorf("unsupported date type %T", value)
+}
+
+// Schedule filter context.
+type Schedule struct {
+	dateKey        string
+	publishDateKey string
+	expiryDateKey  string
+	includeFuture  bool
+	referenceTime  *time.Time
+}
+
+// New creates a new instance of the Schedule filter.
+func New() *Schedule {
+	return &Schedule{
+		dateKey:        "Date",
+		publishDateKey: "PublishDate",
+		expiryDateKey:  "ExpiryDate",
+	}
+}
+
+// DateKey sets the metadata key used to access the content date (default: "Date").
+// This date is used as the publication date if none is specified explicitly.
+func (self *Schedule) DateKey(key string) *Schedule {
+	self.dateKey = key
+	return self
+}
+
+// PublishDateKey sets the metadata key used to access the publication date (default: "PublishDate").
+func (self *Schedule) PublishDateKey(key string) *Schedule {
+	self.publishDateKey = key
+	return self
+}
+
+// ExpiryDateKey sets the metadata key used to access the expiry date (default: "ExpiryDate").
+func (self *Schedule) ExpiryDateKey(key string) *Schedule {
+	self.expiryDateKey = key
+	return self
+}
+
+// IncludeFuture sets whether files with future publication dates are accepted (default: false).
+// This is useful for previewing scheduled content.
+func (self *Schedule) IncludeFuture(includeFuture bool) *Schedule {
+	self.includeFuture = includeFuture
+	return self
+}
+
+// ReferenceTime sets the time that dates are compared against (default: the current time).
+func (self *Schedule) ReferenceTime(referenceTime time.Time) *Schedule {
+	self.referenceTime = &referenceTime
+	return self
+}
+
+func (*Schedule) Name() string {
+	return "schedule"
+}
+
+func (self *Schedule) Accept(file *goldsmith.File) bool {
+	accept, err := self.Check(file)
+	return accept && err == nil
+}
+
+func (self *Schedule) Explain(file *goldsmith.File) *explain.Node {
+	accept, reason, err := self.check(file)
+	return &explain.Node{Filter: self.Name(), Accepted: accept && err == nil, Detail: reason, Err: err}
+}
+
+// Check reports whether the file is published at the reference time, returning
+// an error if any of its dates cannot be interpreted.
+func (self *Schedule) Check(file *goldsmith.File) (bool, error) {
+	accept, _, err := self.check(file)
+	return accept, err
+}
+
+func (self *Schedule) check(file *goldsmith.File) (bool, string, error) {
+	now := time.Now()
+	if self.referenceTime != nil {
+		now = *self.referenceTime
+	}
+
+	publishDate, ok, err := self.getDate(file, self.publishDateKey)
+	if err != nil {
+		return false, "", err
+	}
+
+	if !ok {
+		if publishDate, ok, err = self.getDate(file, self.dateKey); err != nil {
+			return false, "", err
+		}
+	}
+
+	if ok && publishDate.After(now) && !self.includeFuture {
+		return false, fmt.Sprintf("publication date %s is after %s", publishDate.Format(time.RFC3339), now.Format(time.RFC3339)), nil
+	}
+
+	expiryDate, ok, err := self.getDate(file, self.expiryDateKey)
+	if err != nil {
+		return false, "", err
+	}
+
+	if ok && !expiryDate.After(now) {
+		return false, fmt.Sprintf("expiry date %s is not after %s", expiryDate.Format(time.RFC3339), now.Format(time.RFC3339)), nil
+	}
+
+	return true, "published", nil
+}
+
+func (self *Schedule) getDate(file *goldsmith.File, key string) (time.Time, bool, error) {
+	if len(key) == 0 {
+		return time.Time{}, false, nil
+	}
+
+	value, ok := file.Prop(key)
+	if !ok || value == nil {
+		return time.Time{}, false, nil
+	}
+
+	parsed, err := ParseDate(value)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("%s: %s: %w", file.Path(), key, err)
+	}
+
+	return parsed, true, nil
+}
@@ -0,0 +1,21 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"foosoft.net/projects/goldsmith"
+	"foosoft.net/projects/goldsmith-components/harness"
+	"foosoft.net/projects/goldsmith-components/plugins/frontmatter"
+)
+
+func Test(t *testing.T) {
+	harness.Validate(
+		t,
+		func(gs *goldsmith.Goldsmith) {
+			gs.
+				Chain(frontmatter.New()).
+				FilterPush(New().ReferenceTime(time.Date(2020, 6, 1, 0, 0, 0, 0, time.Local)))
+		},
+	)
+}
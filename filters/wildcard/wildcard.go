@@ -1,41 +1,188 @@
 package wildcard
 
 import (
+	"fmt"
+	"path"
 	"strings"
 
 	"foosoft.net/projects/goldsmith"
+	"foosoft.net/projects/goldsmith-components/filters/explain"
 	"github.com/bmatcuk/doublestar/v4"
 )
 
+type matcher struct {
+	source   string
+	adjusted string
+	prefix   string
+	err      error
+}
+
+// patternIndex groups matchers by the first segment of their literal prefix,
+// so that only patterns which can possibly match a path are evaluated.
+type patternIndex struct {
+	bySegment map[string][]*matcher
+	other     []*matcher
+}
+
+func (self *patternIndex) add(pattern *matcher) {
+	if index := strings.Index(pattern.prefix, "/"); index >= 0 {
+		if self.bySegment == nil {
+			self.bySegment = make(map[string][]*matcher)
+		}
+
+		segment := pattern.prefix[:index]
+		self.bySegment[segment] = append(self.bySegment[segment], pattern)
+	} else {
+		self.other = append(self.other, pattern)
+	}
+}
+
+func (self *patternIndex) match(filePath string) *matcher {
+	if self == nil {
+		return nil
+	}
+
+	// Paths without a separator are looked up as a whole, since patterns such
+	// as "blog/**" also match the directory "blog" itself.
+	segment := filePath
+	if index := strings.Index(filePath, "/"); index >= 0 {
+		segment = filePath[:index]
+	}
+
+	if pattern := matchPatterns(self.bySegment[segment], filePath); pattern != nil {
+		return pattern
+	}
+
+	return matchPatterns(self.other, filePath)
+}
+
 type Wildcard struct {
 	wildcards     []string
 	caseSensitive bool
+
+	patterns []*matcher
+	literals map[string]*matcher
+	byExt    map[string]*patternIndex
+	anyExt   *patternIndex
 }
 
+// New creates a new instance of the Wildcard filter. Malformed patterns never
+// match; callers building wildcards from user input must check them with
+// Validate.
 func New(wildcards ...string) *Wildcard {
-	return &Wildcard{wildcards: wildcards}
+	self := &Wildcard{wildcards: wildcards}
+	self.compile()
+	return self
 }
 
 func (self *Wildcard) CaseSensitive(caseSensitive bool) *Wildcard {
-	self.caseSensitive = caseSensitive
+	if self.caseSensitive != caseSensitive {
+		self.caseSensitive = caseSensitive
+		self.compile()
+	}
+
 	return self
 }
 
+// Validate returns an error describing the first malformed pattern, if any.
+// Accept treats malformed patterns as never matching, so plugins which build
+// wildcards from user input, such as configuration files, must call Validate
+// and report the error rather than silently ignoring the pattern.
+func (self *Wildcard) Validate() error {
+	for _, pattern := range self.patterns {
+		if pattern.err != nil {
+			return pattern.err
+		}
+	}
+
+	return nil
+}
+
 func (*Wildcard) Name() string {
 	return "wildcard"
 }
 
+// Accept reports whether the file matches any of the patterns, ignoring those
+// which are malformed.
 func (self *Wildcard) Accept(file *goldsmith.File) bool {
-	filePath := self.adjustCase(file.Path())
+	return self.match(file.Path()) != nil
+}
+
+func (self *Wildcard) Explain(file *goldsmith.File) *explain.Node {
+	node := &explain.Node{Filter: self.Name(), Err: self.Validate()}
+	if pattern := self.match(file.Path()); pattern != nil {
+		node.Accepted = true
+		node.Detail = fmt.Sprintf("matched %q", pattern.source)
+	} else {
+		node.Detail = fmt.Sprintf("no match in %q", self.wildcards)
+	}
+
+	return node
+}
+
+func (self *Wildcard) compile() {
+	self.patterns = nil
+	self.literals = make(map[string]*matcher)
+	self.byExt = make(map[string]*patternIndex)
+	self.anyExt = new(patternIndex)
 
 	for _, wildcard := range self.wildcards {
-		wildcard = self.adjustCase(wildcard)
-		if matched, _ := doublestar.PathMatch(wildcard, filePath); matched {
-			return true
+		pattern := &matcher{source: wildcard, adjusted: self.adjustCase(wildcard)}
+		self.patterns = append(self.patterns, pattern)
+
+		if !doublestar.ValidatePathPattern(wildcard) {
+			pattern.err = fmt.Errorf("malformed pattern %q: %w", wildcard, doublestar.ErrBadPattern)
+			continue
+		}
+
+		metaIndex := strings.IndexAny(pattern.adjusted, "*?[{\\")
+		if metaIndex < 0 {
+			if _, ok := self.literals[pattern.adjusted]; !ok {
+				self.literals[pattern.adjusted] = pattern
+			}
+
+			continue
+		}
+
+		pattern.prefix = pattern.adjusted[:metaIndex]
+		if ext, ok := literalExt(pattern.adjusted); ok {
+			if _, ok := self.byExt[ext]; !ok {
+				self.byExt[ext] = new(patternIndex)
+			}
+
+			self.byExt[ext].add(pattern)
+		} else {
+			self.anyExt.add(pattern)
 		}
 	}
+}
+
+func (self *Wildcard) match(filePath string) *matcher {
+	filePath = self.adjustCase(filePath)
 
-	return false
+	if pattern, ok := self.literals[filePath]; ok {
+		return pattern
+	}
+
+	if pattern := self.byExt[path.Ext(filePath)].match(filePath); pattern != nil {
+		return pattern
+	}
+
+	return self.anyExt.match(filePath)
+}
+
+func matchPatterns(patterns []*matcher, filePath string) *matcher {
+	for _, pattern := range patterns {
+		if !strings.HasPrefix(filePath+"/", pattern.prefix) {
+			continue
+		}
+
+		if matched, _ := doublestar.PathMatch(pattern.adjusted, filePath); matched {
+			return pattern
+		}
+	}
+
+	return nil
 }
 
 func (self *Wildcard) adjustCase(str string) string {
@@ -45,3 +192,17 @@ func (self *Wildcard) adjustCase(str string) string {
 
 	return strings.ToLower(str)
 }
+
+func literalExt(pattern string) (string, bool) {
+	base := pattern[strings.LastIndex(pattern, "/")+1:]
+	if !strings.HasPrefix(base, "*") {
+		return "", false
+	}
+
+	ext := base[1:]
+	if strings.Count(ext, ".") != 1 || !strings.HasPrefix(ext, ".") || strings.ContainsAny(ext, "*?[{\\") {
+		return "", false
+	}
+
+	return ext, true
+}
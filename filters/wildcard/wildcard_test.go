@@ -15,3 +15,47 @@ func Test(t *testing.T) {
 		},
 	)
 }
+
+func TestValidate(t *testing.T) {
+	if err := New("**/*.txt", "*.md").Validate(); err != nil {
+		t.Error(err)
+	}
+
+	if err := New("**/*.txt", "[a-").Validate(); err == nil {
+		t.Error("expected error for malformed pattern")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		wildcards []string
+		path      string
+		matched   bool
+	}{
+		{[]string{"**/*.md"}, "blog/post.md", true},
+		{[]string{"**/*.md"}, "post.md", true},
+		{[]string{"**/*.md"}, "blog/post.markdown", false},
+		{[]string{"*.md"}, "blog/post.md", false},
+		{[]string{"blog/**"}, "blog/2023/post.md", true},
+		{[]string{"blog/**"}, "news/post.md", false},
+		{[]string{"blog/**"}, "blog", true},
+		{[]string{"blog/2023/**"}, "blog/2023", true},
+		{[]string{"blog/**"}, "blogs", false},
+		{[]string{"blog/*.{md,txt}"}, "blog/notes.txt", true},
+		{[]string{"index.html"}, "index.html", true},
+		{[]string{"index.html"}, "blog/index.html", false},
+		{[]string{"*.tar.gz"}, "archive.tar.gz", true},
+		{[]string{"**/*.MD"}, "blog/Post.md", true},
+		{[]string{"[a-", "**/*.txt"}, "notes.txt", true},
+	}
+
+	for _, c := range cases {
+		if matched := New(c.wildcards...).match(c.path) != nil; matched != c.matched {
+			t.Errorf("%q against %q: expected %t", c.wildcards, c.path, c.matched)
+		}
+	}
+
+	if New("**/*.MD").CaseSensitive(true).match("blog/post.md") != nil {
+		t.Error("case sensitive match should fail")
+	}
+}
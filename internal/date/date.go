@@ -0,0 +1,40 @@
+// Package date parses dates in the layouts shared by all plugins and filters,
+// so that a value is understood as a date the same way everywhere.
+package date
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+var layouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02",
+	time.RFC1123Z,
+	time.RFC1123,
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+}
+
+// Parse converts a string in one of the recognized layouts into a time; dates
+// without an explicit time zone are interpreted in the local time zone.
+func Parse(str string) (time.Time, error) {
+	str = strings.TrimSpace(str)
+	for _, layout := range layouts {
+		if date, err := time.ParseInLocation(layout, str, time.Local); err == nil {
+			return date, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized date format %q", str)
+}
@@ -0,0 +1,22 @@
+package date
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(self *testing.T) {
+	expected := time.Date(2023, 5, 1, 0, 0, 0, 0, time.Local)
+	for _, str := range []string{"2023-05-01", " 2023-05-01 ", "2023/05/01", "May 1, 2023", "1 May 2023", "2023-05-01T00:00"} {
+		date, err := Parse(str)
+		if err != nil {
+			self.Errorf("%q: unexpected error %v", str, err)
+		} else if !date.Equal(expected) {
+			self.Errorf("%q: unexpected date %v", str, date)
+		}
+	}
+
+	if _, err := Parse("yesterday"); err == nil {
+		self.Error("expected error for unrecognized date")
+	}
+}
@@ -0,0 +1,33 @@
+// Package slug converts text into the lowercase, hyphenated identifiers used
+// in URLs, so that plugins generating and resolving them agree on their form.
+package slug
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Make converts text into a slug: letters and digits are lowercased, and runs
+// of any other characters become single hyphens, except at either end, so that
+// "Hello, World!" becomes "hello-world".
+func Make(text string) string {
+	var (
+		slug   strings.Builder
+		hyphen bool
+	)
+
+	for _, c := range text {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) {
+			if hyphen && slug.Len() > 0 {
+				slug.WriteRune('-')
+			}
+
+			slug.WriteRune(unicode.ToLower(c))
+			hyphen = false
+		} else {
+			hyphen = true
+		}
+	}
+
+	return slug.String()
+}
@@ -0,0 +1,19 @@
+package slug
+
+import "testing"
+
+func TestMake(self *testing.T) {
+	cases := map[string]string{
+		"Hello World":       "hello-world",
+		"  Hello, World!  ": "hello-world",
+		"Go 1.18 release":   "go-1-18-release",
+		"Über_straße":       "über-straße",
+		"---":               "",
+	}
+
+	for text, expected := range cases {
+		if slug := Make(text); slug != expected {
+			self.Errorf("%q: expected %q, got %q", text, expected, slug)
+		}
+	}
+}
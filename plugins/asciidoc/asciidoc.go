@@ -0,0 +1,116 @@
+// Package asciidoc renders AsciiDoc documents to HTML. The document header is
+// read into props the way the "frontmatter" plugin reads metadata: the title
+// is stored as "Title", and attribute entries are stored under their names in
+// camel case, so that ":layout: page" sets the "Layout" prop used by the
+// "layout" plugin and ":reading-time: 5" sets "ReadingTime". The author and
+// revision lines set the "Author", "Email", "Authors", "Revnumber", "Revdate"
+// and "Revremark" props.
+//
+//	= My homepage
+//	Jane Doe <jane@example.com>
+//	v1.0, 2023-10-01
+//	:layout: page
+//	:tags: best, page, ever
+//
+// The output is plain HTML in the style of the "markdown" plugin, so that it
+// works the same way with other plugins: the title is rendered as a level one
+// heading and paragraphs as "p" elements for the "summary" plugin, and source
+// blocks such as "[source,go]" are marked with "language-" classes for the
+// "syntax" plugin.
+//
+// A solid subset of AsciiDoc is supported: sections, paragraphs, admonitions,
+// lists, description lists, checklists, literal, listing, example, quote,
+// verse, sidebar, open and passthrough blocks, tables, images, inline
+// formatting, attribute references, links, cross references, anchors,
+// footnotes and the "ifdef" and "ifndef" preprocessor directives. Markup
+// which cannot be rendered, such as "include" directives and unknown block
+// macros, causes the build to fail with an error pointing at the offending
+// line.
+//
+// Relative links to other AsciiDoc files are rewritten to point to the HTML
+// files they are rendered to.
+package asciidoc
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+
+	"foosoft.net/projects/goldsmith"
+	"foosoft.net/projects/goldsmith-components/filters/wildcard"
+)
+
+// Asciidoc chainable context.
+type Asciidoc struct{}
+
+// New creates a new instance of the Asciidoc plugin.
+func New() *Asciidoc {
+	return &Asciidoc{}
+}
+
+func (*Asciidoc) Name() string {
+	return "asciidoc"
+}
+
+func (*Asciidoc) Initialize(context *goldsmith.Context) error {
+	context.Filter(wildcard.New("**/*.adoc", "**/*.asciidoc"))
+	return nil
+}
+
+func (*Asciidoc) Process(context *goldsmith.Context, inputFile *goldsmith.File) error {
+	var dataIn bytes.Buffer
+	if _, err := dataIn.ReadFrom(inputFile); err != nil {
+		return err
+	}
+
+	// The header is parsed even if the output is cached, as props are not.
+	doc, err := parseDocument(dataIn.Bytes())
+	if err != nil {
+		return fmt.Errorf("%s: %w", inputFile.Path(), err)
+	}
+
+	outputPath := strings.TrimSuffix(inputFile.Path(), path.Ext(inputFile.Path())) + ".html"
+	if outputFile := context.RetrieveCachedFile(outputPath, inputFile); outputFile != nil {
+		outputFile.CopyProps(inputFile)
+		setProps(outputFile, doc)
+		context.DispatchFile(outputFile)
+		return nil
+	}
+
+	dataOut, err := doc.render()
+	if err != nil {
+		return fmt.Errorf("%s: %w", inputFile.Path(), err)
+	}
+
+	outputFile, err := context.CreateFileFromReader(outputPath, bytes.NewReader(dataOut))
+	if err != nil {
+		return err
+	}
+
+	outputFile.CopyProps(inputFile)
+	setProps(outputFile, doc)
+	context.DispatchAndCacheFile(outputFile, inputFile)
+	return nil
+}
+
+func setProps(file *goldsmith.File, doc *document) {
+	if len(doc.title) > 0 {
+		file.SetProp("Title", doc.title)
+	}
+
+	for name, value := range doc.header {
+		file.SetProp(propKey(name), value)
+	}
+}
+
+// propKey converts the name of an attribute to the key of a prop, such as
+// "reading-time" to "ReadingTime".
+func propKey(name string) string {
+	var key strings.Builder
+	for _, word := range strings.FieldsFunc(name, func(c rune) bool { return c == '-' || c == '_' }) {
+		key.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+
+	return key.String()
+}
@@ -0,0 +1,86 @@
+package asciidoc
+
+import (
+	"errors"
+	"testing"
+
+	"foosoft.net/projects/goldsmith"
+	"foosoft.net/projects/goldsmith-components/harness"
+	"foosoft.net/projects/goldsmith-components/plugins/layout"
+	"foosoft.net/projects/goldsmith-components/plugins/summary"
+	"foosoft.net/projects/goldsmith-components/plugins/syntax"
+)
+
+func Test(self *testing.T) {
+	harness.Validate(
+		self,
+		func(gs *goldsmith.Goldsmith) {
+			gs.Chain(New())
+		},
+	)
+}
+
+func TestPlugins(self *testing.T) {
+	harness.ValidateCase(
+		self,
+		"plugins",
+		func(gs *goldsmith.Goldsmith) {
+			gs.
+				Chain(New()).
+				Chain(summary.New()).
+				Chain(syntax.New().Placement(syntax.PlaceInline)).
+				Chain(layout.New())
+		},
+	)
+}
+
+func TestErrors(self *testing.T) {
+	cases := []struct {
+		source string
+		line   int
+		err    string
+	}{
+		{"= Title\n\ninclude::other.adoc[]\n", 3, `include directive is not supported`},
+		{"Text\n\ntoc::[]\n", 3, `unsupported block macro "toc"`},
+		{"See <<missing>>.\n", 1, `unknown cross reference "missing"`},
+		{"----\nunterminated\n", 1, `unterminated delimited block`},
+		{"ifdef::name[]\ntext\n", 1, `unterminated conditional`},
+		{"|===\n|a |b\n|c\n|===\n", 3, `incomplete table row`},
+	}
+
+	for _, c := range cases {
+		doc, err := parseDocument([]byte(c.source))
+		if err == nil {
+			_, err = doc.render()
+		}
+
+		var docErr *Error
+		if !errors.As(err, &docErr) {
+			self.Errorf("%q: expected asciidoc error, got %v", c.source, err)
+			continue
+		}
+
+		if docErr.Line != c.line || docErr.Message != c.err {
+			self.Errorf("%q: unexpected error %v", c.source, err)
+		}
+	}
+}
+
+func TestNulCharacters(self *testing.T) {
+	sources := []string{"\x000\x00\n", "a \x007\x00 +b+\n", "Term\x00::\x000\x00\n"}
+	for _, source := range sources {
+		doc, err := parseDocument([]byte(source))
+		if err == nil {
+			_, err = doc.render()
+		}
+
+		if err != nil {
+			self.Errorf("%q: unexpected error %v", source, err)
+		}
+	}
+
+	renderer := &renderer{attributes: copyAttributes(defaultAttributes)}
+	if html, err := renderer.inline("a \x005\x00 +b+", 1); err != nil || html != "a \x005\x00 b" {
+		self.Errorf("unexpected inline output %q, %v", html, err)
+	}
+}
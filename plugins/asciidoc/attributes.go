@@ -0,0 +1,256 @@
+package asciidoc
+
+import (
+	"regexp"
+	"strings"
+)
+
+// defaultAttributes are the built-in attributes of every document, which can
+// be referenced or overridden but are not stored as props.
+var defaultAttributes = map[string]string{
+	"idprefix":          "_",
+	"idseparator":       "_",
+	"note-caption":      "Note",
+	"tip-caption":       "Tip",
+	"important-caption": "Important",
+	"warning-caption":   "Warning",
+	"caution-caption":   "Caution",
+	"empty":             "",
+	"sp":                " ",
+	"nbsp":              "\u00a0",
+	"zwsp":              "\u200b",
+	"wj":                "\u2060",
+	"apos":              "'",
+	"quot":              "\"",
+	"lsquo":             "‘",
+	"rsquo":             "’",
+	"ldquo":             "“",
+	"rdquo":             "”",
+	"deg":               "°",
+	"plus":              "+",
+	"brvbar":            "¦",
+	"vbar":              "|",
+	"amp":               "&",
+	"lt":                "<",
+	"gt":                ">",
+	"startsb":           "[",
+	"endsb":             "]",
+	"caret":             "^",
+	"asterisk":          "*",
+	"tilde":             "~",
+	"backslash":         "\\",
+	"backtick":          "`",
+	"two-colons":        "::",
+	"two-semicolons":    ";;",
+	"cpp":               "C++",
+}
+
+var (
+	attributeEntryExp     = regexp.MustCompile(`^:(!?\w[\w-]*!?):(?:[ \t]+(.*))?$`)
+	attributeReferenceExp = regexp.MustCompile(`\\?\{(\w[\w-]*)\}`)
+	attributeNameExp      = regexp.MustCompile(`^[\w-]+$`)
+)
+
+func copyAttributes(attributes map[string]string) map[string]string {
+	result := make(map[string]string, len(attributes))
+	for name, value := range attributes {
+		result[name] = value
+	}
+
+	return result
+}
+
+// parseAttributeEntry parses an attribute entry such as ":name: value", whose
+// value may continue on following lines ending with " \". Entries of the form
+// ":name!:" unset the attribute.
+func parseAttributeEntry(lines []line, i int) (string, string, bool, int, bool) {
+	matches := attributeEntryExp.FindStringSubmatch(lines[i].text)
+	if matches == nil {
+		return "", "", false, 0, false
+	}
+
+	name := matches[1]
+	unset := strings.HasPrefix(name, "!") || strings.HasSuffix(name, "!")
+	name = strings.Trim(name, "!")
+
+	value := matches[2]
+	for i++; strings.HasSuffix(value, " \\") && i < len(lines); i++ {
+		value = strings.TrimSuffix(value, "\\") + strings.TrimSpace(lines[i].text)
+	}
+
+	return strings.ToLower(name), strings.TrimSpace(value), unset, i, true
+}
+
+// replaceAttributes replaces attribute references such as "{name}" in text.
+// References to undefined attributes are left as they are, and references
+// escaped by a backslash are kept without it.
+func replaceAttributes(text string, attributes map[string]string, escape func(string) string) string {
+	return attributeReferenceExp.ReplaceAllStringFunc(text, func(reference string) string {
+		if strings.HasPrefix(reference, "\\") {
+			return reference[1:]
+		}
+
+		value, ok := attributes[strings.ToLower(reference[1:len(reference)-1])]
+		if !ok {
+			return reference
+		}
+
+		if escape != nil {
+			return escape(value)
+		}
+
+		return value
+	})
+}
+
+// attributes are the attributes of a block, given in square brackets on the
+// lines preceding it or within a block macro.
+type attributes struct {
+	style      string
+	id         string
+	reftext    string
+	roles      []string
+	options    map[string]bool
+	positional []string
+	named      map[string]string
+}
+
+func newAttributes() *attributes {
+	return &attributes{options: make(map[string]bool), named: make(map[string]string)}
+}
+
+func (self *attributes) merge(other *attributes) {
+	if len(other.style) > 0 {
+		self.style = other.style
+	}
+
+	if len(other.id) > 0 {
+		self.id = other.id
+	}
+
+	self.roles = append(self.roles, other.roles...)
+	for option := range other.options {
+		self.options[option] = true
+	}
+
+	if len(other.positional) > 0 {
+		self.positional = other.positional
+	}
+
+	for key, value := range other.named {
+		self.named[key] = value
+	}
+}
+
+// parseAttributeList parses a list of attributes separated by commas. The
+// first positional attribute may use the shorthand "style#id.role%option".
+func parseAttributeList(text string) *attributes {
+	result := newAttributes()
+	for index, entry := range splitAttributeList(text) {
+		if separator := strings.Index(entry, "="); separator > 0 && attributeNameExp.MatchString(strings.TrimSpace(entry[:separator])) {
+			name := strings.TrimSpace(entry[:separator])
+			value := unquote(strings.TrimSpace(entry[separator+1:]))
+			switch name {
+			case "id":
+				result.id = value
+			case "role":
+				result.roles = append(result.roles, strings.Fields(value)...)
+			case "options", "opts":
+				for _, option := range strings.Split(value, ",") {
+					result.options[strings.TrimSpace(option)] = true
+				}
+			default:
+				result.named[name] = value
+			}
+
+			continue
+		}
+
+		entry = unquote(entry)
+		result.positional = append(result.positional, entry)
+		if index == 0 {
+			result.parseShorthand(entry)
+		}
+	}
+
+	return result
+}
+
+func (self *attributes) parseShorthand(text string) {
+	if strings.ContainsAny(text, " \t") {
+		return
+	}
+
+	var (
+		kind  byte
+		start int
+	)
+
+	for index := 0; index <= len(text); index++ {
+		if index < len(text) && !strings.ContainsRune("#.%", rune(text[index])) {
+			continue
+		}
+
+		value := text[start:index]
+		switch kind {
+		case 0:
+			self.style = value
+		case '#':
+			self.id = value
+		case '.':
+			self.roles = append(self.roles, value)
+		case '%':
+			self.options[value] = true
+		}
+
+		if index < len(text) {
+			kind = text[index]
+			start = index + 1
+		}
+	}
+}
+
+// splitAttributeList splits a list of attributes at commas which are not
+// enclosed in quotes.
+func splitAttributeList(text string) []string {
+	if len(strings.TrimSpace(text)) == 0 {
+		return nil
+	}
+
+	var (
+		entries []string
+		quote   rune
+		start   int
+	)
+
+	for index, c := range text {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && isValueStart(text[start:index]):
+			quote = c
+		case c == ',':
+			entries = append(entries, strings.TrimSpace(text[start:index]))
+			start = index + 1
+		}
+	}
+
+	return append(entries, strings.TrimSpace(text[start:]))
+}
+
+// isValueStart reports whether a quote at the end of the given prefix of an
+// entry starts its value, either at the start of the entry or after "name=".
+func isValueStart(prefix string) bool {
+	prefix = strings.TrimSpace(prefix)
+	return len(prefix) == 0 || strings.HasSuffix(prefix, "=")
+}
+
+func unquote(text string) string {
+	if len(text) >= 2 && (text[0] == '"' || text[0] == '\'') && text[len(text)-1] == text[0] {
+		return text[1 : len(text)-1]
+	}
+
+	return text
+}
@@ -0,0 +1,261 @@
+package asciidoc
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	preprocessorExp = regexp.MustCompile(`^(\\)?(ifdef|ifndef|ifeval|endif|include)::([^\[]*)\[(.*)\]$`)
+	titleExp        = regexp.MustCompile(`^=[ \t]+(\S.*?)[ \t]*$`)
+	authorExp       = regexp.MustCompile(`^([^<]+?)(?:[ \t]+<([^>]+)>)?$`)
+	versionExp      = regexp.MustCompile(`^[vV]\d`)
+)
+
+// document is a parsed document header, followed by the lines of the body.
+type document struct {
+	title      string
+	titleLine  int
+	header     map[string]string
+	attributes map[string]string
+	body       []line
+}
+
+// parseDocument preprocesses a document and parses its header, leaving the
+// body to be parsed when the document is rendered.
+func parseDocument(source []byte) (*document, error) {
+	lines, err := preprocess(splitLines(string(source)))
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &document{header: make(map[string]string), attributes: copyAttributes(defaultAttributes)}
+
+	// Comments and attribute entries may precede the document title.
+	i := doc.parseAttributeEntries(lines, 0, true)
+	if i < len(lines) {
+		if matches := titleExp.FindStringSubmatch(lines[i].text); matches != nil {
+			doc.title = replaceAttributes(matches[1], doc.attributes, nil)
+			doc.titleLine = lines[i].number
+			i++
+
+			if i < len(lines) && isHeaderLine(lines[i]) {
+				doc.parseAuthors(lines[i].text)
+				i++
+
+				if i < len(lines) && isHeaderLine(lines[i]) {
+					doc.parseRevision(lines[i].text)
+					i++
+				}
+			}
+
+			i = doc.parseAttributeEntries(lines, i, false)
+		}
+	}
+
+	doc.body = lines[i:]
+	return doc, nil
+}
+
+func isHeaderLine(l line) bool {
+	return !isBlank(l) && !isComment(l) && !attributeEntryExp.MatchString(l.text)
+}
+
+// parseAttributeEntries parses the attribute entries of the header, which
+// ends at the first blank line after the title.
+func (self *document) parseAttributeEntries(lines []line, i int, skipBlank bool) int {
+	for i < len(lines) {
+		switch {
+		case isBlank(lines[i]):
+			if !skipBlank {
+				return i
+			}
+
+			i++
+		case isComment(lines[i]):
+			i++
+		default:
+			name, value, unset, next, ok := parseAttributeEntry(lines, i)
+			if !ok {
+				return i
+			}
+
+			if unset {
+				delete(self.attributes, name)
+				delete(self.header, name)
+			} else {
+				value = replaceAttributes(value, self.attributes, nil)
+				self.attributes[name] = value
+				self.header[name] = value
+			}
+
+			i = next
+		}
+	}
+
+	return i
+}
+
+// parseAuthors parses an author line such as "Jane Doe <jane@example.com>;
+// John Doe", setting the "author", "email" and "authors" attributes.
+func (self *document) parseAuthors(text string) {
+	var names []string
+	for index, author := range strings.Split(text, ";") {
+		matches := authorExp.FindStringSubmatch(strings.TrimSpace(author))
+		if matches == nil {
+			continue
+		}
+
+		names = append(names, matches[1])
+		if index == 0 {
+			self.setHeader("author", matches[1])
+			if len(matches[2]) > 0 {
+				self.setHeader("email", matches[2])
+			}
+		}
+	}
+
+	self.setHeader("authors", strings.Join(names, ", "))
+}
+
+// parseRevision parses a revision line such as "v1.0, 2023-10-01: Remark",
+// setting the "revnumber", "revdate" and "revremark" attributes.
+func (self *document) parseRevision(text string) {
+	if index := strings.Index(text, ":"); index >= 0 {
+		self.setHeader("revremark", strings.TrimSpace(text[index+1:]))
+		text = text[:index]
+	}
+
+	if index := strings.Index(text, ","); index >= 0 {
+		self.setHeader("revnumber", strings.TrimLeft(strings.TrimSpace(text[:index]), "vV"))
+		self.setHeader("revdate", strings.TrimSpace(text[index+1:]))
+	} else if versionExp.MatchString(text) {
+		self.setHeader("revnumber", strings.TrimSpace(text[1:]))
+	} else {
+		self.setHeader("revdate", strings.TrimSpace(text))
+	}
+}
+
+func (self *document) setHeader(name, value string) {
+	if len(value) > 0 {
+		self.attributes[name] = value
+		self.header[name] = value
+	}
+}
+
+type condition struct {
+	line   int
+	name   string
+	active bool
+}
+
+// preprocess applies conditional preprocessor directives, which include or
+// exclude lines depending on whether attributes are set.
+func preprocess(lines []line) ([]line, error) {
+	var (
+		result     []line
+		conditions []condition
+		defined    = copyAttributes(defaultAttributes)
+	)
+
+	active := func() bool {
+		for _, condition := range conditions {
+			if !condition.active {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	for _, current := range lines {
+		matches := preprocessorExp.FindStringSubmatch(current.text)
+		if matches == nil || len(matches[1]) > 0 {
+			if !active() {
+				continue
+			}
+
+			if matches != nil {
+				current.text = current.text[1:]
+			} else if entry := attributeEntryExp.FindStringSubmatch(current.text); entry != nil {
+				name := strings.ToLower(strings.Trim(entry[1], "!"))
+				if strings.Contains(entry[1], "!") {
+					delete(defined, name)
+				} else {
+					defined[name] = entry[2]
+				}
+			}
+
+			result = append(result, current)
+			continue
+		}
+
+		directive, target, content := matches[2], matches[3], matches[4]
+		switch directive {
+		case "ifdef", "ifndef":
+			if len(target) == 0 {
+				return nil, &Error{current.number, directive + " requires an attribute name"}
+			}
+
+			value := isDefined(target, defined)
+			if directive == "ifndef" {
+				value = !value
+			}
+
+			// The single-line form includes the content in brackets.
+			if len(content) > 0 {
+				if value && active() {
+					result = append(result, line{content, current.number})
+				}
+
+				continue
+			}
+
+			conditions = append(conditions, condition{current.number, target, value})
+		case "endif":
+			if len(conditions) == 0 {
+				return nil, &Error{current.number, "endif without matching ifdef or ifndef"}
+			}
+
+			if last := conditions[len(conditions)-1]; len(target) > 0 && target != last.name {
+				return nil, &Error{current.number, fmt.Sprintf("endif %q does not match %q", target, last.name)}
+			}
+
+			conditions = conditions[:len(conditions)-1]
+		case "ifeval", "include":
+			if active() {
+				return nil, &Error{current.number, directive + " directive is not supported"}
+			}
+		}
+	}
+
+	if len(conditions) > 0 {
+		return nil, &Error{conditions[len(conditions)-1].line, "unterminated conditional"}
+	}
+
+	return result, nil
+}
+
+// isDefined evaluates the attribute names of a conditional, which must all be
+// defined if separated by "+" and any of which must be defined if separated
+// by ",".
+func isDefined(names string, defined map[string]string) bool {
+	if strings.Contains(names, "+") {
+		for _, name := range strings.Split(names, "+") {
+			if _, ok := defined[strings.ToLower(name)]; !ok {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	for _, name := range strings.Split(names, ",") {
+		if _, ok := defined[strings.ToLower(name)]; ok {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,470 @@
+package asciidoc
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type quote struct {
+	mark        string
+	constrained bool
+	tag         string
+}
+
+// quotes are the formatting marks in the order they are replaced, so that
+// unconstrained marks such as "**" take precedence over constrained ones.
+var quotes = []quote{
+	{"**", false, "strong"},
+	{"*", true, "strong"},
+	{"``", false, "code"},
+	{"`", true, "code"},
+	{"__", false, "em"},
+	{"_", true, "em"},
+	{"##", false, "mark"},
+	{"#", true, "mark"},
+	{"^", false, "sup"},
+	{"~", false, "sub"},
+}
+
+var (
+	tripleplusExp   = regexp.MustCompile(`(?s)\+\+\+(.+?)\+\+\+`)
+	passMacroExp    = regexp.MustCompile(`(?s)pass:[a-z,]*\[((?:[^\]\\]|\\.)*)\]`)
+	literalMonoExp  = regexp.MustCompile("(?s)`\\+(.+?)\\+`")
+	doubleplusExp   = regexp.MustCompile(`(?s)\+\+(.+?)\+\+`)
+	roleExp         = regexp.MustCompile(`\[\.([\w-]+(?:\.[\w-]+)*)\]$`)
+	placeholderExp  = regexp.MustCompile("\x00(\\d+)\x00")
+	hardBreakExp    = regexp.MustCompile(`(?m) \+$`)
+	apostropheExp   = regexp.MustCompile(`(\w)'(\w)`)
+	unescapeExp     = regexp.MustCompile("\\\\([*_`#^~+\\[])")
+	inlineImageExp  = regexp.MustCompile(`image:([^:\s\[][^\s\[]*)\[((?:[^\]\\]|\\.)*)\]`)
+	footnoteExp     = regexp.MustCompile(`footnote:([\w-]*)\[((?:[^\]\\]|\\.)*)\]`)
+	linkMacroExp    = regexp.MustCompile(`(link|mailto):([^\s\[]+)\[((?:[^\]\\]|\\.)*)\]`)
+	urlExp          = regexp.MustCompile(`(?:https?|ftp|irc)://[^\s\[\]]+(?:\[(?:[^\]\\]|\\.)*\])?`)
+	xrefMacroExp    = regexp.MustCompile(`xref:([^\s\[]+)\[((?:[^\]\\]|\\.)*)\]`)
+	xrefExp         = regexp.MustCompile(`&lt;&lt;([\w:#./-][^,]*?)(?:,\s*(.+?))?&gt;&gt;`)
+	inlineAnchorExp = regexp.MustCompile(`\[\[([\w:][\w:.-]*)(?:,\s*[^\]]+)?\]\]|anchor:([\w:][\w:.-]*)\[[^\]]*\]`)
+)
+
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	replacer    = strings.NewReplacer(
+		"(C)", "&#169;",
+		"(R)", "&#174;",
+		"(TM)", "&#8482;",
+		" -- ", "&#8201;&#8212;&#8201;",
+		"...", "&#8230;&#8203;",
+		"-&gt;", "&#8594;",
+		"=&gt;", "&#8658;",
+		"&lt;-", "&#8592;",
+		"&lt;=", "&#8656;",
+	)
+)
+
+func escapeText(text string) string {
+	return textEscaper.Replace(text)
+}
+
+// escapeAttribute escapes text which has already been escaped by escapeText
+// for use in an attribute value.
+func escapeAttribute(text string) string {
+	return strings.ReplaceAll(text, "\"", "&#34;")
+}
+
+func isWord(c rune) bool {
+	return c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+func runeBefore(text string, index int) rune {
+	if index == 0 {
+		return 0
+	}
+
+	c, _ := utf8.DecodeLastRuneInString(text[:index])
+	return c
+}
+
+func runeAt(text string, index int) rune {
+	if index >= len(text) {
+		return 0
+	}
+
+	c, _ := utf8.DecodeRuneInString(text[index:])
+	return c
+}
+
+// replaceQuoted replaces text enclosed by a formatting mark. Constrained marks
+// must be bounded by characters other than letters and digits, and may be
+// preceded by roles such as "[.role]".
+func replaceQuoted(text string, q quote, format func(content, roles string) string) string {
+	// Superscript and subscript text may not contain spaces.
+	spaceless := q.mark == "^" || q.mark == "~"
+
+	var builder strings.Builder
+	plain := 0
+	for index := 0; index < len(text); index++ {
+		if !strings.HasPrefix(text[index:], q.mark) || index > 0 && text[index-1] == '\\' {
+			continue
+		}
+
+		start := index
+		roles := ""
+		if matches := roleExp.FindStringSubmatchIndex(text[plain:index]); matches != nil {
+			start = plain + matches[0]
+			roles = strings.ReplaceAll(text[plain+matches[2]:plain+matches[3]], ".", " ")
+		}
+
+		before := runeBefore(text, start)
+		contentStart := index + len(q.mark)
+		if contentStart >= len(text) || unicode.IsSpace(runeAt(text, contentStart)) {
+			continue
+		}
+
+		if q.constrained && (isWord(before) || before == ';' || before == ':' || before == '}') {
+			continue
+		}
+
+		end := -1
+		for candidate := contentStart + 1; candidate <= len(text)-len(q.mark); candidate++ {
+			if spaceless && unicode.IsSpace(runeAt(text, candidate-1)) {
+				break
+			}
+
+			if !strings.HasPrefix(text[candidate:], q.mark) || unicode.IsSpace(runeBefore(text, candidate)) || text[candidate-1] == '\\' {
+				continue
+			}
+
+			if q.constrained && isWord(runeAt(text, candidate+len(q.mark))) {
+				continue
+			}
+
+			end = candidate
+			break
+		}
+
+		if end < 0 {
+			continue
+		}
+
+		builder.WriteString(text[plain:start])
+		builder.WriteString(format(text[contentStart:end], roles))
+		index = end + len(q.mark) - 1
+		plain = index + 1
+	}
+
+	builder.WriteString(text[plain:])
+	return builder.String()
+}
+
+func (q quote) format(content, roles string) string {
+	tag := q.tag
+	if tag == "mark" && len(roles) > 0 {
+		tag = "span"
+	}
+
+	if len(roles) > 0 {
+		return fmt.Sprintf("<%s class=\"%s\">%s</%s>", tag, roles, content, tag)
+	}
+
+	return fmt.Sprintf("<%s>%s</%s>", tag, content, tag)
+}
+
+// inline renders inline markup to HTML, applying substitutions in the same
+// order as Asciidoctor: passthroughs are set aside, special characters are
+// escaped, and quotes, attribute references, replacements and macros follow.
+func (self *renderer) inline(text string, number int) (string, error) {
+	var passthroughs []string
+	pass := func(html string) string {
+		passthroughs = append(passthroughs, html)
+		return "\x00" + strconv.Itoa(len(passthroughs)-1) + "\x00"
+	}
+
+	text = tripleplusExp.ReplaceAllStringFunc(text, func(match string) string {
+		return pass(match[3 : len(match)-3])
+	})
+
+	text = passMacroExp.ReplaceAllStringFunc(text, func(match string) string {
+		content := passMacroExp.FindStringSubmatch(match)[1]
+		return pass(strings.ReplaceAll(content, "\\]", "]"))
+	})
+
+	text = literalMonoExp.ReplaceAllStringFunc(text, func(match string) string {
+		return pass("<code>" + escapeText(match[2:len(match)-2]) + "</code>")
+	})
+
+	text = doubleplusExp.ReplaceAllStringFunc(text, func(match string) string {
+		return pass(escapeText(match[2 : len(match)-2]))
+	})
+
+	text = replaceQuoted(text, quote{mark: "+", constrained: true}, func(content, roles string) string {
+		return pass(escapeText(content))
+	})
+
+	text = escapeText(text)
+	for _, q := range quotes {
+		text = replaceQuoted(text, q, q.format)
+	}
+
+	text = replaceAttributes(text, self.attributes, escapeText)
+	text = replacer.Replace(text)
+	text = apostropheExp.ReplaceAllString(text, "$1&#8217;$2")
+
+	text, err := self.replaceMacros(text, number)
+	if err != nil {
+		return "", err
+	}
+
+	text = hardBreakExp.ReplaceAllString(text, "<br>")
+	text = unescapeExp.ReplaceAllString(text, "$1")
+	text = placeholderExp.ReplaceAllStringFunc(text, func(match string) string {
+		index, err := strconv.Atoi(match[1 : len(match)-1])
+		if err != nil || index >= len(passthroughs) {
+			return match
+		}
+
+		return passthroughs[index]
+	})
+
+	return text, nil
+}
+
+func (self *renderer) replaceMacros(text string, number int) (string, error) {
+	var err error
+	replace := func(exp *regexp.Regexp, replacement func(matches []string) (string, error)) {
+		if err != nil {
+			return
+		}
+
+		text = exp.ReplaceAllStringFunc(text, func(match string) string {
+			if err != nil {
+				return match
+			}
+
+			var result string
+			result, err = replacement(exp.FindStringSubmatch(match))
+			return result
+		})
+	}
+
+	replace(inlineImageExp, func(matches []string) (string, error) {
+		attrs := parseAttributeList(matches[2])
+		return self.imageTag(matches[1], attrs.positional, attrs.named), nil
+	})
+
+	replace(footnoteExp, func(matches []string) (string, error) {
+		return self.footnote(matches[1], matches[2], number)
+	})
+
+	replace(linkMacroExp, func(matches []string) (string, error) {
+		target, content := matches[2], strings.ReplaceAll(matches[3], "\\]", "]")
+		if matches[1] == "mailto" {
+			target = "mailto:" + target
+		}
+
+		if len(content) == 0 {
+			content = strings.TrimPrefix(target, "mailto:")
+		}
+
+		return fmt.Sprintf("<a href=\"%s\">%s</a>", escapeAttribute(rewriteLink(target)), content), nil
+	})
+
+	text = replaceURLs(text)
+
+	replace(xrefMacroExp, func(matches []string) (string, error) {
+		return self.xref(matches[1], matches[2], number)
+	})
+
+	replace(xrefExp, func(matches []string) (string, error) {
+		return self.xref(matches[1], matches[2], number)
+	})
+
+	replace(inlineAnchorExp, func(matches []string) (string, error) {
+		id := matches[1] + matches[2]
+		return fmt.Sprintf("<a id=\"%s\"></a>", escapeAttribute(id)), nil
+	})
+
+	return text, err
+}
+
+// replaceURLs links bare URLs and URLs followed by link text in brackets,
+// except those within the attributes or content of links.
+func replaceURLs(text string) string {
+	var builder strings.Builder
+	plain := 0
+	for _, indices := range urlExp.FindAllStringIndex(text, -1) {
+		start, end := indices[0], indices[1]
+		if before := runeBefore(text, start); isWord(before) || strings.ContainsRune("/\"'=>", before) {
+			continue
+		}
+
+		url, content := text[start:end], ""
+		if strings.HasSuffix(url, "]") {
+			bracket := strings.Index(url, "[")
+			url, content = url[:bracket], url[bracket+1:len(url)-1]
+		} else {
+			// Angle brackets and trailing punctuation are not part of bare URLs.
+			if index := strings.Index(url, "&gt;"); index >= 0 {
+				url = url[:index]
+			}
+
+			url = strings.TrimRight(url, ".,;:!?)")
+			end = start + len(url)
+			if strings.HasSuffix(text[:start], "&lt;") && strings.HasPrefix(text[end:], "&gt;") {
+				start -= len("&lt;")
+				end += len("&gt;")
+			}
+		}
+
+		if len(content) == 0 {
+			content = url
+		}
+
+		builder.WriteString(text[plain:start])
+		fmt.Fprintf(&builder, "<a href=\"%s\">%s</a>", escapeAttribute(url), strings.ReplaceAll(content, "\\]", "]"))
+		plain = end
+	}
+
+	builder.WriteString(text[plain:])
+	return builder.String()
+}
+
+// xref renders a cross reference to an element of the document, such as
+// "<<id>>", or to another document, such as "<<guide.adoc#setup,Setup>>".
+func (self *renderer) xref(target, content string, number int) (string, error) {
+	documentPath, fragment := target, ""
+	index := strings.Index(target, "#")
+	if index >= 0 {
+		documentPath, fragment = target[:index], target[index+1:]
+	}
+
+	extension := strings.ToLower(path.Ext(documentPath))
+	if len(documentPath) > 0 && (index >= 0 || extension == ".adoc" || extension == ".asciidoc") {
+		if len(extension) == 0 {
+			documentPath += ".adoc"
+		}
+
+		href := rewriteLink(documentPath)
+		if len(fragment) > 0 {
+			href += "#" + fragment
+		}
+
+		if len(content) == 0 {
+			content = href
+		}
+
+		return fmt.Sprintf("<a href=\"%s\">%s</a>", escapeAttribute(href), content), nil
+	}
+
+	id := strings.TrimPrefix(target, "#")
+	reftext, ok := self.parser.references[id]
+	if !ok {
+		return "", &Error{number, fmt.Sprintf("unknown cross reference %q", id)}
+	}
+
+	if len(content) == 0 {
+		if len(reftext) == 0 {
+			content = "[" + escapeText(id) + "]"
+		} else {
+			var err error
+			if content, err = self.inline(reftext, number); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return fmt.Sprintf("<a href=\"#%s\">%s</a>", escapeAttribute(id), content), nil
+}
+
+func (self *renderer) footnote(name, content string, number int) (string, error) {
+	index := -1
+	if len(name) > 0 {
+		for i, footnote := range self.footnotes {
+			if footnote.name == name {
+				index = i
+			}
+		}
+	}
+
+	if index >= 0 {
+		return fmt.Sprintf("<sup class=\"footnote\">[<a class=\"footnote\" href=\"#_footnotedef_%d\">%d</a>]</sup>", index+1, index+1), nil
+	}
+
+	if len(content) == 0 {
+		return "", &Error{number, fmt.Sprintf("unknown footnote %q", name)}
+	}
+
+	self.footnotes = append(self.footnotes, footnote{name, strings.ReplaceAll(content, "\\]", "]")})
+	index = len(self.footnotes)
+	return fmt.Sprintf("<sup class=\"footnote\">[<a id=\"_footnoteref_%d\" class=\"footnote\" href=\"#_footnotedef_%d\">%d</a>]</sup>", index, index, index), nil
+}
+
+// imageTag renders an image with its alternative text and size given as
+// positional attributes. The "imagesdir" attribute is prepended to relative
+// paths.
+func (self *renderer) imageTag(target string, positional []string, named map[string]string) string {
+	attrs := make(map[string]string)
+	for index, key := range []string{"alt", "width", "height"} {
+		if index < len(positional) && len(positional[index]) > 0 {
+			attrs[key] = positional[index]
+		}
+	}
+
+	for key, value := range named {
+		attrs[key] = value
+	}
+
+	alt, ok := attrs["alt"]
+	if !ok {
+		// Images without alternative text are described by their file name.
+		alt = strings.TrimSuffix(path.Base(target), path.Ext(target))
+		alt = strings.NewReplacer("-", " ", "_", " ").Replace(alt)
+	}
+
+	src := target
+	if dir, ok := self.attributes["imagesdir"]; ok && len(dir) > 0 && !isAbsolute(src) {
+		src = strings.TrimSuffix(dir, "/") + "/" + src
+	}
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "<img src=\"%s\" alt=\"%s\"", escapeAttribute(src), escapeAttribute(alt))
+	for _, key := range []string{"width", "height"} {
+		if value, ok := attrs[key]; ok {
+			fmt.Fprintf(&builder, " %s=\"%s\"", key, escapeAttribute(value))
+		}
+	}
+
+	builder.WriteString(">")
+	if link, ok := attrs["link"]; ok {
+		return fmt.Sprintf("<a href=\"%s\">%s</a>", escapeAttribute(rewriteLink(link)), builder.String())
+	}
+
+	return builder.String()
+}
+
+func isAbsolute(target string) bool {
+	return strings.HasPrefix(target, "/") || strings.Contains(target, "://") || strings.HasPrefix(target, "data:")
+}
+
+// rewriteLink points relative links to AsciiDoc sources at the HTML files
+// they are rendered to.
+func rewriteLink(destination string) string {
+	if isAbsolute(destination) || strings.HasPrefix(destination, "mailto:") {
+		return destination
+	}
+
+	linkPath, suffix := destination, ""
+	if index := strings.IndexAny(destination, "?#"); index >= 0 {
+		linkPath, suffix = destination[:index], destination[index:]
+	}
+
+	switch strings.ToLower(path.Ext(linkPath)) {
+	case ".adoc", ".asciidoc":
+		return strings.TrimSuffix(linkPath, path.Ext(linkPath)) + ".html" + suffix
+	}
+
+	return destination
+}
@@ -0,0 +1,824 @@
+package asciidoc
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type nodeKind int
+
+const (
+	kindSection nodeKind = iota
+	kindParagraph
+	kindLiteral
+	kindListing
+	kindAdmonition
+	kindExample
+	kindQuote
+	kindVerse
+	kindSidebar
+	kindOpen
+	kindPass
+	kindUnorderedList
+	kindOrderedList
+	kindDescriptionList
+	kindListItem
+	kindTable
+	kindBreak
+	kindImage
+	kindAttribute
+)
+
+type node struct {
+	kind     nodeKind
+	line     int
+	text     string
+	title    string
+	id       string
+	roles    []string
+	style    string
+	level    int
+	attrs    map[string]string
+	options  map[string]bool
+	rows     [][]*cell
+	header   bool
+	footer   bool
+	children []*node
+}
+
+type line struct {
+	text   string
+	number int
+}
+
+// Error describes invalid or unsupported markup at a line of a document.
+type Error struct {
+	Line    int
+	Message string
+}
+
+func (self *Error) Error() string {
+	return fmt.Sprintf("line %d: %s", self.Line, self.Message)
+}
+
+// admonitionTypes are the styles of admonition paragraphs and blocks.
+var admonitionTypes = map[string]bool{
+	"NOTE":      true,
+	"TIP":       true,
+	"IMPORTANT": true,
+	"WARNING":   true,
+	"CAUTION":   true,
+}
+
+var (
+	commentExp        = regexp.MustCompile(`^//(?:[^/]|$)`)
+	blockAnchorExp    = regexp.MustCompile(`^\[\[([\w:][\w:.-]*)(?:,\s*(.+))?\]\]$`)
+	blockAttributeExp = regexp.MustCompile(`^\[([^\[\]].*)?\]$`)
+	blockTitleExp     = regexp.MustCompile(`^\.([^\s.].*)$`)
+	sectionExp        = regexp.MustCompile(`^(={1,6})[ \t]+(\S.*?)(?:[ \t]+=+)?[ \t]*$`)
+	breakExp          = regexp.MustCompile(`^'{3,}$`)
+	blockMacroExp     = regexp.MustCompile(`^(\w[\w-]*)::(\S*?)\[(.*)\]$`)
+	admonitionExp     = regexp.MustCompile(`^(NOTE|TIP|IMPORTANT|WARNING|CAUTION):[ \t]+(.*)$`)
+	unorderedExp      = regexp.MustCompile(`^[ \t]*(-|\*{1,5})[ \t]+(\S.*)$`)
+	orderedExp        = regexp.MustCompile(`^[ \t]*(\.{1,5}|\d{1,9}\.)[ \t]+(\S.*)$`)
+	descriptionExp    = regexp.MustCompile(`^[ \t]*(\S.*?)(:{2,4}|;;)(?:[ \t]+(.*))?$`)
+	checkExp          = regexp.MustCompile(`^\[([ xX*])\][ \t]+`)
+)
+
+type parser struct {
+	attributes map[string]string
+	ids        map[string]int
+	references map[string]string
+	listDepth  int
+}
+
+func newParser(attributes map[string]string) *parser {
+	return &parser{
+		attributes: copyAttributes(attributes),
+		ids:        make(map[string]int),
+		references: make(map[string]string),
+	}
+}
+
+func splitLines(text string) []line {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.TrimPrefix(text, "\ufeff")
+
+	// NUL characters are reserved for the placeholders of passthroughs.
+	text = strings.ReplaceAll(text, "\x00", "")
+
+	var lines []line
+	for index, l := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		lines = append(lines, line{strings.TrimRight(l, " \t"), index + 1})
+	}
+
+	return lines
+}
+
+func isBlank(l line) bool {
+	return len(strings.TrimSpace(l.text)) == 0
+}
+
+func isComment(l line) bool {
+	return commentExp.MatchString(l.text)
+}
+
+func joinLines(lines []line) string {
+	var texts []string
+	for _, l := range lines {
+		texts = append(texts, l.text)
+	}
+
+	return strings.Join(texts, "\n")
+}
+
+// dedent removes the indentation common to all non-blank lines.
+func dedent(lines []line) []line {
+	indent := -1
+	for _, l := range lines {
+		if isBlank(l) {
+			continue
+		}
+
+		if width := len(l.text) - len(strings.TrimLeft(l.text, " \t")); indent < 0 || width < indent {
+			indent = width
+		}
+	}
+
+	var result []line
+	for _, l := range lines {
+		if len(l.text) >= indent && indent > 0 {
+			l.text = l.text[indent:]
+		}
+
+		result = append(result, l)
+	}
+
+	return result
+}
+
+// delimiter checks if a line opens or closes a delimited block.
+func delimiter(text string) bool {
+	if text == "--" || strings.HasPrefix(text, "```") {
+		return true
+	}
+
+	if strings.HasPrefix(text, "|===") {
+		return strings.Trim(text[1:], "=") == ""
+	}
+
+	if len(text) < 4 || !strings.ContainsRune("-.=_*+/", rune(text[0])) {
+		return false
+	}
+
+	return strings.Trim(text, text[:1]) == ""
+}
+
+// metadata holds the block title and attributes which apply to the next block.
+type metadata struct {
+	title string
+	attrs *attributes
+}
+
+func (self *parser) parseBlocks(lines []line) ([]*node, error) {
+	var (
+		nodes []*node
+		meta  = &metadata{attrs: newAttributes()}
+	)
+
+	for i := 0; i < len(lines); {
+		if isBlank(lines[i]) {
+			i++
+			continue
+		}
+
+		node, next, err := self.parseBlock(lines, i, meta)
+		if err != nil {
+			return nil, err
+		}
+
+		if node != nil {
+			nodes = append(nodes, node)
+			meta = &metadata{attrs: newAttributes()}
+		}
+
+		i = next
+	}
+
+	return nodes, nil
+}
+
+// parseAttached parses the block attached to a list item by a "+" line.
+func (self *parser) parseAttached(lines []line, i int) (*node, int, error) {
+	meta := &metadata{attrs: newAttributes()}
+	for i < len(lines) && !isBlank(lines[i]) {
+		node, next, err := self.parseBlock(lines, i, meta)
+		if err != nil || node != nil {
+			return node, next, err
+		}
+
+		i = next
+	}
+
+	return nil, i, nil
+}
+
+// parseBlock parses the block starting at a line, returning a nil node for
+// lines which only carry metadata for the following block.
+func (self *parser) parseBlock(lines []line, i int, meta *metadata) (*node, int, error) {
+	current := lines[i]
+	text := current.text
+
+	if text == "////" || strings.HasPrefix(text, "////") && strings.Trim(text, "/") == "" {
+		end := findClosing(lines, i)
+		if end < 0 {
+			return nil, 0, &Error{current.number, "unterminated comment block"}
+		}
+
+		return nil, end + 1, nil
+	}
+
+	if isComment(current) {
+		return nil, i + 1, nil
+	}
+
+	if name, value, unset, next, ok := parseAttributeEntry(lines, i); ok {
+		value = replaceAttributes(value, self.attributes, nil)
+		self.setAttribute(name, value, unset)
+
+		node := &node{kind: kindAttribute, line: current.number, id: name, text: value}
+		if unset {
+			node.options = map[string]bool{"unset": true}
+		}
+
+		return node, next, nil
+	}
+
+	if matches := blockAnchorExp.FindStringSubmatch(text); matches != nil {
+		meta.attrs.id = matches[1]
+		meta.attrs.reftext = matches[2]
+		return nil, i + 1, nil
+	}
+
+	if matches := blockAttributeExp.FindStringSubmatch(text); matches != nil {
+		meta.attrs.merge(parseAttributeList(matches[1]))
+		return nil, i + 1, nil
+	}
+
+	if matches := blockTitleExp.FindStringSubmatch(text); matches != nil {
+		meta.title = matches[1]
+		return nil, i + 1, nil
+	}
+
+	if matches := sectionExp.FindStringSubmatch(text); matches != nil {
+		return self.section(current, len(matches[1])-1, matches[2], meta), i + 1, nil
+	}
+
+	if breakExp.MatchString(text) {
+		return self.block(&node{kind: kindBreak, line: current.number}, meta), i + 1, nil
+	}
+
+	if text == "<<<" {
+		return nil, i + 1, nil
+	}
+
+	if matches := blockMacroExp.FindStringSubmatch(text); matches != nil {
+		node, err := self.parseBlockMacro(current, matches[1], matches[2], matches[3], meta)
+		return node, i + 1, err
+	}
+
+	if delimiter(text) {
+		return self.parseDelimited(lines, i, meta)
+	}
+
+	if unorderedExp.MatchString(text) || orderedExp.MatchString(text) || descriptionExp.MatchString(text) {
+		return self.parseList(lines, i, nil, meta)
+	}
+
+	if text[0] == ' ' || text[0] == '\t' {
+		end := i
+		for end < len(lines) && !isBlank(lines[end]) {
+			end++
+		}
+
+		node := &node{kind: kindLiteral, line: current.number, text: joinLines(dedent(lines[i:end]))}
+		return self.block(node, meta), end, nil
+	}
+
+	return self.parseParagraph(lines, i, meta)
+}
+
+// block applies the metadata preceding a block to its node.
+func (self *parser) block(node *node, meta *metadata) *node {
+	node.title = meta.title
+	node.roles = meta.attrs.roles
+	node.options = meta.attrs.options
+	if len(meta.attrs.id) > 0 {
+		node.id = meta.attrs.id
+		self.ids[node.id]++
+		if len(meta.attrs.reftext) > 0 {
+			self.references[node.id] = meta.attrs.reftext
+		} else if _, ok := self.references[node.id]; !ok {
+			self.references[node.id] = meta.title
+		}
+	}
+
+	if node.attrs == nil {
+		node.attrs = make(map[string]string)
+	}
+
+	for key, value := range meta.attrs.named {
+		if _, ok := node.attrs[key]; !ok {
+			node.attrs[key] = value
+		}
+	}
+
+	return node
+}
+
+func (self *parser) setAttribute(name, value string, unset bool) {
+	if unset {
+		delete(self.attributes, name)
+	} else {
+		self.attributes[name] = value
+	}
+}
+
+func (self *parser) section(current line, level int, title string, meta *metadata) *node {
+	node := self.block(&node{kind: kindSection, line: current.number, level: level, text: title}, meta)
+	if len(node.id) == 0 {
+		node.id = self.uniqueId(title)
+	}
+
+	self.references[node.id] = title
+	if len(meta.attrs.reftext) > 0 {
+		self.references[node.id] = meta.attrs.reftext
+	}
+
+	return node
+}
+
+// uniqueId derives an identifier from a title in the way of Asciidoctor,
+// using the "idprefix" and "idseparator" attributes.
+func (self *parser) uniqueId(title string) string {
+	prefix, separator := self.attributes["idprefix"], self.attributes["idseparator"]
+
+	var (
+		id      strings.Builder
+		pending bool
+	)
+
+	id.WriteString(prefix)
+	for _, c := range title {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) {
+			if pending && id.Len() > len(prefix) {
+				id.WriteString(separator)
+			}
+
+			id.WriteRune(unicode.ToLower(c))
+			pending = false
+		} else {
+			pending = true
+		}
+	}
+
+	base := id.String()
+	count := self.ids[base]
+	self.ids[base] = count + 1
+	if count > 0 {
+		return base + separator + strconv.Itoa(count+1)
+	}
+
+	return base
+}
+
+func (self *parser) parseBlockMacro(current line, name, target, args string, meta *metadata) (*node, error) {
+	switch name {
+	case "image":
+		if len(target) == 0 {
+			return nil, &Error{current.number, "image requires a target"}
+		}
+
+		attrs := parseAttributeList(args)
+		node := &node{kind: kindImage, line: current.number, attrs: map[string]string{"src": target}}
+		for index, key := range []string{"alt", "width", "height"} {
+			if index < len(attrs.positional) && len(attrs.positional[index]) > 0 {
+				node.attrs[key] = attrs.positional[index]
+			}
+		}
+
+		for key, value := range attrs.named {
+			node.attrs[key] = value
+		}
+
+		meta.attrs.merge(attrs)
+		return self.block(node, meta), nil
+	case "include":
+		return nil, &Error{current.number, "include directive is not supported"}
+	}
+
+	return nil, &Error{current.number, fmt.Sprintf("unsupported block macro %q", name)}
+}
+
+// findClosing finds the line closing the delimited block opened at a line.
+func findClosing(lines []line, i int) int {
+	for end := i + 1; end < len(lines); end++ {
+		if lines[end].text == lines[i].text {
+			return end
+		}
+	}
+
+	return -1
+}
+
+func (self *parser) parseDelimited(lines []line, i int, meta *metadata) (*node, int, error) {
+	current := lines[i]
+	opening := current.text
+
+	closing := opening
+	if strings.HasPrefix(opening, "```") {
+		closing = "```"
+	}
+
+	end := -1
+	for index := i + 1; index < len(lines); index++ {
+		if lines[index].text == closing {
+			end = index
+			break
+		}
+	}
+
+	if end < 0 {
+		return nil, 0, &Error{current.number, "unterminated delimited block"}
+	}
+
+	content := lines[i+1 : end]
+	style := meta.attrs.style
+
+	// Blocks within list items are parsed independently of the list.
+	listDepth := self.listDepth
+	self.listDepth = 0
+	defer func() { self.listDepth = listDepth }()
+
+	var (
+		node = &node{line: current.number}
+		err  error
+	)
+
+	switch {
+	case strings.HasPrefix(opening, "```"):
+		node.kind = kindListing
+		node.style = "source"
+		node.attrs = map[string]string{"language": strings.TrimSpace(opening[3:])}
+		node.text = joinLines(content)
+	case strings.HasPrefix(opening, "|"):
+		node, err = self.parseTable(current, content, meta)
+	case opening[0] == '-' && opening != "--":
+		node.kind = kindListing
+		node.text = joinLines(content)
+		self.listing(node, meta)
+	case opening[0] == '.':
+		node.kind = kindLiteral
+		node.text = joinLines(content)
+	case opening[0] == '+':
+		node.kind = kindPass
+		node.text = joinLines(content)
+	case opening[0] == '=' && admonitionTypes[style]:
+		node.kind = kindAdmonition
+		node.style = style
+		node.children, err = self.parseBlocks(content)
+	case opening[0] == '=':
+		node.kind = kindExample
+		node.children, err = self.parseBlocks(content)
+	case opening[0] == '_' && style == "verse":
+		node.kind = kindVerse
+		node.text = joinLines(content)
+		self.attribution(node, meta)
+	case opening[0] == '_':
+		node.kind = kindQuote
+		node.children, err = self.parseBlocks(content)
+		self.attribution(node, meta)
+	case opening[0] == '*':
+		node.kind = kindSidebar
+		node.children, err = self.parseBlocks(content)
+	default:
+		node.kind = kindOpen
+		switch {
+		case admonitionTypes[style]:
+			node.kind = kindAdmonition
+			node.style = style
+		case style == "quote":
+			node.kind = kindQuote
+			self.attribution(node, meta)
+		case style == "sidebar":
+			node.kind = kindSidebar
+		case style == "example":
+			node.kind = kindExample
+		}
+
+		node.children, err = self.parseBlocks(content)
+	}
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return self.block(node, meta), end + 1, nil
+}
+
+// listing sets the language of a listing block, which is taken from its
+// attributes or the "source-language" attribute of the document.
+func (self *parser) listing(node *node, meta *metadata) {
+	style := meta.attrs.style
+	if style != "source" && (len(style) > 0 || len(meta.attrs.positional) < 2) {
+		return
+	}
+
+	node.style = "source"
+	node.attrs = map[string]string{"language": self.attributes["source-language"]}
+	if len(meta.attrs.positional) > 1 && len(meta.attrs.positional[1]) > 0 {
+		node.attrs["language"] = meta.attrs.positional[1]
+	}
+}
+
+// attribution sets the author and source of a quote.
+func (self *parser) attribution(node *node, meta *metadata) {
+	node.attrs = make(map[string]string)
+	for index, key := range []string{"attribution", "citetitle"} {
+		if index+1 < len(meta.attrs.positional) && len(meta.attrs.positional[index+1]) > 0 {
+			node.attrs[key] = meta.attrs.positional[index+1]
+		}
+	}
+}
+
+// paragraphEnd checks if a line ends the paragraph which precedes it.
+func (self *parser) paragraphEnd(l line) bool {
+	if isBlank(l) || delimiter(l.text) {
+		return true
+	}
+
+	if self.listDepth > 0 {
+		return l.text == "+" || isListItem(l.text)
+	}
+
+	return false
+}
+
+func (self *parser) parseParagraph(lines []line, i int, meta *metadata) (*node, int, error) {
+	current := lines[i]
+
+	var texts []string
+	end := i
+	for ; end < len(lines) && (end == i || !self.paragraphEnd(lines[end])); end++ {
+		if !isComment(lines[end]) {
+			texts = append(texts, strings.TrimSpace(lines[end].text))
+		}
+	}
+
+	text := strings.Join(texts, "\n")
+	paragraph := &node{kind: kindParagraph, line: current.number, text: text}
+	style := meta.attrs.style
+
+	if matches := admonitionExp.FindStringSubmatch(text); matches != nil && len(style) == 0 {
+		style = matches[1]
+		text = matches[2]
+	}
+
+	switch {
+	case admonitionTypes[style]:
+		paragraph.kind = kindAdmonition
+		paragraph.style = style
+		paragraph.children = []*node{{kind: kindParagraph, line: current.number, text: text}}
+	case style == "source" || style == "listing":
+		paragraph.kind = kindListing
+		paragraph.text = joinLines(lines[i:end])
+		self.listing(paragraph, meta)
+	case style == "literal":
+		paragraph.kind = kindLiteral
+		paragraph.text = joinLines(lines[i:end])
+	case style == "quote":
+		paragraph.kind = kindQuote
+		paragraph.children = []*node{{kind: kindParagraph, line: current.number, text: text}}
+		self.attribution(paragraph, meta)
+	case style == "verse":
+		paragraph.kind = kindVerse
+		self.attribution(paragraph, meta)
+	case style == "pass":
+		paragraph.kind = kindPass
+		paragraph.text = joinLines(lines[i:end])
+	}
+
+	return self.block(paragraph, meta), end, nil
+}
+
+func isListItem(text string) bool {
+	_, _, _, ok := listMarker(text)
+	return ok
+}
+
+// listMarker returns the marker of a list item, the kind of list it belongs
+// to and the text following the marker.
+func listMarker(text string) (string, nodeKind, string, bool) {
+	if matches := unorderedExp.FindStringSubmatch(text); matches != nil {
+		return matches[1], kindUnorderedList, matches[2], true
+	}
+
+	if matches := orderedExp.FindStringSubmatch(text); matches != nil {
+		marker := matches[1]
+		if marker[0] != '.' {
+			marker = "1."
+		}
+
+		return marker, kindOrderedList, matches[2], true
+	}
+
+	if matches := descriptionExp.FindStringSubmatch(text); matches != nil && !blockMacroExp.MatchString(text) {
+		return matches[2], kindDescriptionList, matches[1] + "\x00" + matches[3], true
+	}
+
+	return "", 0, "", false
+}
+
+// orderedStyles maps the styles of ordered lists to the types of HTML lists,
+// with nested lists using the styles in order.
+var orderedStyles = []struct {
+	style    string
+	htmlType string
+}{
+	{"arabic", ""},
+	{"loweralpha", "a"},
+	{"lowerroman", "i"},
+	{"upperalpha", "A"},
+	{"upperroman", "I"},
+}
+
+// parseList parses a list whose items share the marker of the first item.
+// Items with other markers start nested lists, unless one of the enclosing
+// lists uses that marker.
+func (self *parser) parseList(lines []line, i int, markers []string, meta *metadata) (*node, int, error) {
+	first := lines[i]
+	marker, kind, _, _ := listMarker(first.text)
+	list := &node{kind: kind, line: first.number, attrs: make(map[string]string)}
+	markers = append(markers, marker)
+
+	self.listDepth++
+	defer func() { self.listDepth-- }()
+
+	for i < len(lines) {
+		itemMarker, _, _, ok := listMarker(lines[i].text)
+		if !ok || itemMarker != marker {
+			break
+		}
+
+		item, next, err := self.parseListItem(lines, i, markers)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		list.children = append(list.children, item)
+		i = next
+
+		// Lists may continue after blank lines, but a line comment separates
+		// adjacent lists.
+		j := i
+		for j < len(lines) && isBlank(lines[j]) {
+			j++
+		}
+
+		if j < len(lines) {
+			if nextMarker, _, _, ok := listMarker(lines[j].text); ok && nextMarker == marker {
+				i = j
+			}
+		}
+	}
+
+	if kind == kindOrderedList {
+		self.orderedList(list, first, marker, meta)
+	}
+
+	if kind == kindDescriptionList && meta.attrs.style == "horizontal" {
+		meta.attrs.roles = append(meta.attrs.roles, "horizontal")
+	}
+
+	return self.block(list, meta), i, nil
+}
+
+// orderedList sets the numbering of an ordered list from the number of its
+// first item, the depth of its marker or its style.
+func (self *parser) orderedList(list *node, first line, marker string, meta *metadata) {
+	if marker == "1." {
+		number := strings.TrimSuffix(orderedExp.FindStringSubmatch(first.text)[1], ".")
+		if value, _ := strconv.Atoi(number); value > 1 {
+			list.attrs["start"] = strconv.Itoa(value)
+		}
+	} else if depth := len(marker) - 1; depth < len(orderedStyles) {
+		list.attrs["type"] = orderedStyles[depth].htmlType
+	}
+
+	for _, style := range orderedStyles {
+		if meta.attrs.style == style.style {
+			list.attrs["type"] = style.htmlType
+		}
+	}
+
+	if start, ok := meta.attrs.named["start"]; ok {
+		list.attrs["start"] = start
+	}
+
+	if len(list.attrs["type"]) == 0 {
+		delete(list.attrs, "type")
+	}
+}
+
+func (self *parser) parseListItem(lines []line, i int, markers []string) (*node, int, error) {
+	current := lines[i]
+	_, kind, text, _ := listMarker(current.text)
+	item := &node{kind: kindListItem, line: current.number}
+
+	if kind == kindDescriptionList {
+		parts := strings.SplitN(text, "\x00", 2)
+		item.title = parts[0]
+		text = parts[1]
+	} else if matches := checkExp.FindStringSubmatch(text); matches != nil && kind == kindUnorderedList {
+		item.options = map[string]bool{"checkbox": true, "checked": matches[1] != " "}
+		text = text[len(matches[0]):]
+	}
+
+	// The text of an item continues until the next item, a blank line or a
+	// list continuation. The description of a term may start on the next line.
+	j := i + 1
+	if len(text) == 0 && kind == kindDescriptionList {
+		for j < len(lines) && isBlank(lines[j]) {
+			j++
+		}
+
+		if j < len(lines) && !isListItem(lines[j].text) && lines[j].text != "+" && !delimiter(lines[j].text) {
+			text = strings.TrimSpace(lines[j].text)
+			j++
+		}
+	}
+
+	texts := []string{text}
+	for ; j < len(lines) && !self.paragraphEnd(lines[j]); j++ {
+		if !isComment(lines[j]) {
+			texts = append(texts, strings.TrimSpace(lines[j].text))
+		}
+	}
+
+	if text := strings.Join(texts, "\n"); len(text) > 0 {
+		item.children = append(item.children, &node{kind: kindParagraph, line: current.number, text: text})
+	}
+
+	for j < len(lines) {
+		if lines[j].text == "+" {
+			block, next, err := self.parseAttached(lines, j+1)
+			if err != nil {
+				return nil, 0, err
+			}
+
+			if block != nil {
+				item.children = append(item.children, block)
+			}
+
+			j = next
+			continue
+		}
+
+		k := j
+		for k < len(lines) && isBlank(lines[k]) {
+			k++
+		}
+
+		marker, _, _, ok := listMarker(lines[min(k, len(lines)-1)].text)
+		if k == len(lines) || !ok {
+			break
+		}
+
+		for _, enclosing := range markers {
+			if marker == enclosing {
+				return item, j, nil
+			}
+		}
+
+		list, next, err := self.parseList(lines, k, markers, &metadata{attrs: newAttributes()})
+		if err != nil {
+			return nil, 0, err
+		}
+
+		item.children = append(item.children, list)
+		j = next
+	}
+
+	return item, j, nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
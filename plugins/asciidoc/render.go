@@ -0,0 +1,543 @@
+package asciidoc
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type footnote struct {
+	name string
+	text string
+}
+
+type renderer struct {
+	parser     *parser
+	attributes map[string]string
+	footnotes  []footnote
+	buff       bytes.Buffer
+}
+
+// render renders the document to HTML. The title is rendered as a level one
+// heading, and footnotes are collected at the end of the document.
+func (self *document) render() ([]byte, error) {
+	parser := newParser(self.attributes)
+	nodes, err := parser.parseBlocks(self.body)
+	if err != nil {
+		return nil, err
+	}
+
+	renderer := &renderer{parser: parser, attributes: copyAttributes(self.attributes)}
+	if len(self.title) > 0 {
+		title, err := renderer.inline(self.title, self.titleLine)
+		if err != nil {
+			return nil, err
+		}
+
+		renderer.write("<h1>%s</h1>\n", title)
+	}
+
+	if err := renderer.blocks(nodes); err != nil {
+		return nil, err
+	}
+
+	if len(renderer.footnotes) > 0 {
+		renderer.write("<div class=\"footnotes\">\n<hr>\n")
+		for index, footnote := range renderer.footnotes {
+			renderer.write("<div class=\"footnote\" id=\"_footnotedef_%d\">\n<a href=\"#_footnoteref_%d\">%d</a>. %s\n</div>\n", index+1, index+1, index+1, footnote.text)
+		}
+
+		renderer.write("</div>\n")
+	}
+
+	return renderer.buff.Bytes(), nil
+}
+
+func (self *renderer) write(format string, args ...interface{}) {
+	fmt.Fprintf(&self.buff, format, args...)
+}
+
+// attrs renders the id and roles of a node, along with additional classes, as
+// HTML attributes.
+func attrs(node *node, classes ...string) string {
+	var builder strings.Builder
+	if len(node.id) > 0 {
+		fmt.Fprintf(&builder, " id=\"%s\"", escapeAttribute(escapeText(node.id)))
+	}
+
+	if classes = append(classes, node.roles...); len(classes) > 0 {
+		fmt.Fprintf(&builder, " class=\"%s\"", escapeAttribute(escapeText(strings.Join(classes, " "))))
+	}
+
+	return builder.String()
+}
+
+func (self *renderer) blocks(nodes []*node) error {
+	for _, node := range nodes {
+		if err := self.block(node); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// title renders the title of a block which has no caption of its own.
+func (self *renderer) title(node *node) error {
+	if len(node.title) == 0 {
+		return nil
+	}
+
+	title, err := self.inline(node.title, node.line)
+	if err != nil {
+		return err
+	}
+
+	self.write("<div class=\"title\">%s</div>\n", title)
+	return nil
+}
+
+func (self *renderer) block(node *node) error {
+	switch node.kind {
+	case kindAttribute:
+		// Attribute entries in the body apply to the content which follows them.
+		if node.options["unset"] {
+			delete(self.attributes, node.id)
+		} else {
+			self.attributes[node.id] = node.text
+		}
+	case kindSection:
+		text, err := self.inline(node.text, node.line)
+		if err != nil {
+			return err
+		}
+
+		level := node.level + 1
+		if level > 6 {
+			level = 6
+		}
+
+		self.write("<h%d%s>%s</h%d>\n", level, attrs(node), text, level)
+	case kindParagraph:
+		if err := self.title(node); err != nil {
+			return err
+		}
+
+		text, err := self.inline(node.text, node.line)
+		if err != nil {
+			return err
+		}
+
+		if node.options["hardbreaks"] {
+			text = strings.ReplaceAll(text, "\n", "<br>\n")
+		}
+
+		self.write("<p%s>%s</p>\n", attrs(node), text)
+	case kindLiteral:
+		if err := self.title(node); err != nil {
+			return err
+		}
+
+		self.write("<pre%s>%s\n</pre>\n", attrs(node), escapeText(node.text))
+	case kindListing:
+		if err := self.title(node); err != nil {
+			return err
+		}
+
+		switch language := node.attrs["language"]; {
+		case len(language) > 0:
+			self.write("<pre%s><code class=\"language-%s\">%s\n</code></pre>\n", attrs(node), escapeAttribute(escapeText(language)), escapeText(node.text))
+		case node.style == "source":
+			self.write("<pre%s><code>%s\n</code></pre>\n", attrs(node), escapeText(node.text))
+		default:
+			self.write("<pre%s>%s\n</pre>\n", attrs(node), escapeText(node.text))
+		}
+	case kindPass:
+		self.write("%s\n", node.text)
+	case kindAdmonition:
+		class := strings.ToLower(node.style)
+		title := node.title
+		if len(title) == 0 {
+			title = self.attributes[class+"-caption"]
+		}
+
+		text, err := self.inline(title, node.line)
+		if err != nil {
+			return err
+		}
+
+		self.write("<aside%s>\n<p class=\"admonition-title\">%s</p>\n", attrs(node, "admonition", class), text)
+		return self.container(node.children, "</aside>\n")
+	case kindExample:
+		self.write("<div%s>\n", attrs(node, "example"))
+		if err := self.title(node); err != nil {
+			return err
+		}
+
+		return self.container(node.children, "</div>\n")
+	case kindSidebar:
+		self.write("<aside%s>\n", attrs(node, "sidebar"))
+		if len(node.title) > 0 {
+			title, err := self.inline(node.title, node.line)
+			if err != nil {
+				return err
+			}
+
+			self.write("<p class=\"sidebar-title\">%s</p>\n", title)
+		}
+
+		return self.container(node.children, "</aside>\n")
+	case kindQuote, kindVerse:
+		if err := self.title(node); err != nil {
+			return err
+		}
+
+		if node.kind == kindVerse {
+			text, err := self.inline(node.text, node.line)
+			if err != nil {
+				return err
+			}
+
+			self.write("<blockquote%s>\n<pre>%s</pre>\n", attrs(node, "verse"), text)
+		} else {
+			self.write("<blockquote%s>\n", attrs(node))
+			if err := self.blocks(node.children); err != nil {
+				return err
+			}
+		}
+
+		if err := self.attribution(node); err != nil {
+			return err
+		}
+
+		self.write("</blockquote>\n")
+	case kindOpen:
+		if len(node.id) == 0 && len(node.roles) == 0 && len(node.title) == 0 {
+			return self.blocks(node.children)
+		}
+
+		self.write("<div%s>\n", attrs(node))
+		if err := self.title(node); err != nil {
+			return err
+		}
+
+		return self.container(node.children, "</div>\n")
+	case kindUnorderedList, kindOrderedList:
+		return self.list(node)
+	case kindDescriptionList:
+		return self.descriptionList(node)
+	case kindTable:
+		return self.table(node)
+	case kindBreak:
+		self.write("<hr>\n")
+	case kindImage:
+		return self.image(node)
+	}
+
+	return nil
+}
+
+func (self *renderer) container(children []*node, close string) error {
+	if err := self.blocks(children); err != nil {
+		return err
+	}
+
+	self.buff.WriteString(close)
+	return nil
+}
+
+// attribution renders the author and source of a quote.
+func (self *renderer) attribution(node *node) error {
+	attribution, citetitle := node.attrs["attribution"], node.attrs["citetitle"]
+	if len(attribution) == 0 && len(citetitle) == 0 {
+		return nil
+	}
+
+	self.write("<footer>")
+	if len(attribution) > 0 {
+		text, err := self.inline(attribution, node.line)
+		if err != nil {
+			return err
+		}
+
+		self.write("&#8212; %s", text)
+		if len(citetitle) > 0 {
+			self.write(", ")
+		}
+	}
+
+	if len(citetitle) > 0 {
+		text, err := self.inline(citetitle, node.line)
+		if err != nil {
+			return err
+		}
+
+		self.write("<cite>%s</cite>", text)
+	}
+
+	self.write("</footer>\n")
+	return nil
+}
+
+// isSimple checks if the content of an item consists of at most one paragraph,
+// optionally followed by nested lists, so that it can be rendered compactly.
+func isSimple(children []*node) bool {
+	if len(children) == 0 {
+		return true
+	}
+
+	if children[0].kind != kindParagraph || len(children[0].title) > 0 {
+		return false
+	}
+
+	for _, child := range children[1:] {
+		switch child.kind {
+		case kindUnorderedList, kindOrderedList, kindDescriptionList:
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// items renders the content of a list item or description.
+func (self *renderer) items(children []*node) error {
+	if !isSimple(children) {
+		self.write("\n")
+		return self.blocks(children)
+	}
+
+	if len(children) == 0 {
+		return nil
+	}
+
+	text, err := self.inline(children[0].text, children[0].line)
+	if err != nil {
+		return err
+	}
+
+	self.buff.WriteString(text)
+	if len(children) > 1 {
+		self.write("\n")
+		return self.blocks(children[1:])
+	}
+
+	return nil
+}
+
+func (self *renderer) list(node *node) error {
+	if err := self.title(node); err != nil {
+		return err
+	}
+
+	tag := "ul"
+	if node.kind == kindOrderedList {
+		tag = "ol"
+	}
+
+	self.write("<%s%s", tag, attrs(node))
+	var keys []string
+	for key := range node.attrs {
+		if key == "start" || key == "type" {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+	for _, key := range keys {
+		self.write(" %s=\"%s\"", key, escapeAttribute(escapeText(node.attrs[key])))
+	}
+
+	self.write(">\n")
+	for _, item := range node.children {
+		self.write("<li>")
+		if item.options["checkbox"] {
+			if item.options["checked"] {
+				self.write("<input checked=\"\" disabled=\"\" type=\"checkbox\"> ")
+			} else {
+				self.write("<input disabled=\"\" type=\"checkbox\"> ")
+			}
+		}
+
+		if err := self.items(item.children); err != nil {
+			return err
+		}
+
+		self.write("</li>\n")
+	}
+
+	self.write("</%s>\n", tag)
+	return nil
+}
+
+func (self *renderer) descriptionList(node *node) error {
+	if err := self.title(node); err != nil {
+		return err
+	}
+
+	self.write("<dl%s>\n", attrs(node))
+	for _, item := range node.children {
+		term, err := self.inline(item.title, item.line)
+		if err != nil {
+			return err
+		}
+
+		self.write("<dt>%s</dt>\n", term)
+		if len(item.children) > 0 {
+			self.write("<dd>")
+			if err := self.items(item.children); err != nil {
+				return err
+			}
+
+			self.write("</dd>\n")
+		}
+	}
+
+	self.write("</dl>\n")
+	return nil
+}
+
+func (self *renderer) table(node *node) error {
+	self.write("<table%s>\n", attrs(node))
+	if len(node.title) > 0 {
+		title, err := self.inline(node.title, node.line)
+		if err != nil {
+			return err
+		}
+
+		self.write("<caption>%s</caption>\n", title)
+	}
+
+	body := node.rows
+	if node.header && len(body) > 0 {
+		if err := self.rows("thead", body[:1], "th"); err != nil {
+			return err
+		}
+
+		body = body[1:]
+	}
+
+	var footer [][]*cell
+	if node.footer && len(body) > 0 {
+		body, footer = body[:len(body)-1], body[len(body)-1:]
+	}
+
+	if err := self.rows("tbody", body, "td"); err != nil {
+		return err
+	}
+
+	if err := self.rows("tfoot", footer, "td"); err != nil {
+		return err
+	}
+
+	self.write("</table>\n")
+	return nil
+}
+
+func (self *renderer) rows(section string, rows [][]*cell, tag string) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	self.write("<%s>\n", section)
+	for _, row := range rows {
+		self.write("<tr>\n")
+		for _, cell := range row {
+			cellTag := tag
+			if cell.style == "h" {
+				cellTag = "th"
+			}
+
+			self.write("<%s", cellTag)
+			if cell.colspan > 1 {
+				self.write(" colspan=\"%d\"", cell.colspan)
+			}
+
+			self.write(">")
+			if err := self.cell(cell); err != nil {
+				return err
+			}
+
+			self.write("</%s>\n", cellTag)
+		}
+
+		self.write("</tr>\n")
+	}
+
+	self.write("</%s>\n", section)
+	return nil
+}
+
+// cellStyles maps the styles of table cells to the elements their paragraphs
+// are enclosed in.
+var cellStyles = map[string]string{
+	"e": "em",
+	"s": "strong",
+	"m": "code",
+}
+
+func (self *renderer) cell(cell *cell) error {
+	if cell.style == "a" || cell.style == "l" {
+		self.write("\n")
+		return self.blocks(cell.children)
+	}
+
+	var paragraphs []string
+	for _, child := range cell.children {
+		text, err := self.inline(child.text, child.line)
+		if err != nil {
+			return err
+		}
+
+		if tag, ok := cellStyles[cell.style]; ok {
+			text = fmt.Sprintf("<%s>%s</%s>", tag, text, tag)
+		}
+
+		paragraphs = append(paragraphs, text)
+	}
+
+	if len(paragraphs) == 1 {
+		self.buff.WriteString(paragraphs[0])
+		return nil
+	}
+
+	self.write("\n")
+	for _, paragraph := range paragraphs {
+		self.write("<p>%s</p>\n", paragraph)
+	}
+
+	return nil
+}
+
+func (self *renderer) image(node *node) error {
+	var positional []string
+	for _, key := range []string{"alt", "width", "height"} {
+		positional = append(positional, escapeText(node.attrs[key]))
+	}
+
+	named := make(map[string]string)
+	if link, ok := node.attrs["link"]; ok {
+		named["link"] = escapeText(link)
+	}
+
+	img := self.imageTag(escapeText(node.attrs["src"]), positional, named)
+	if len(node.title) == 0 {
+		if len(node.id) > 0 || len(node.roles) > 0 {
+			self.write("<div%s>%s</div>\n", attrs(node), img)
+		} else {
+			self.write("%s\n", img)
+		}
+
+		return nil
+	}
+
+	title, err := self.inline(node.title, node.line)
+	if err != nil {
+		return err
+	}
+
+	self.write("<figure%s>\n%s\n<figcaption>%s</figcaption>\n</figure>\n", attrs(node), img, title)
+	return nil
+}
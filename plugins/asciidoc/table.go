@@ -0,0 +1,237 @@
+package asciidoc
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type cell struct {
+	line     int
+	style    string
+	colspan  int
+	children []*node
+}
+
+var (
+	cellSpecExp   = regexp.MustCompile(`(?:^|[ \t])((?:(\d+)\*)?(?:(\d+)?(?:\.(\d+))?\+)?(?:[<^>])?(?:\.[<^>])?([adehlmsv])?)$`)
+	columnSpecExp = regexp.MustCompile(`^(?:(\d+)\*)?(?:[<^>])?(?:\.[<^>])?(?:\d+%?|~)?([adehlmsv])?$`)
+)
+
+// parseColumns parses the "cols" attribute of a table, which either gives the
+// number of columns or a list of column specifiers, returning their styles.
+func parseColumns(current line, value string) ([]string, error) {
+	if count, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+		return make([]string, count), nil
+	}
+
+	var styles []string
+	for _, spec := range strings.FieldsFunc(value, func(c rune) bool { return c == ',' || c == ';' }) {
+		matches := columnSpecExp.FindStringSubmatch(strings.TrimSpace(spec))
+		if matches == nil {
+			return nil, &Error{current.number, "invalid column specifier " + strconv.Quote(spec)}
+		}
+
+		repeat := 1
+		if len(matches[1]) > 0 {
+			repeat, _ = strconv.Atoi(matches[1])
+		}
+
+		for ; repeat > 0; repeat-- {
+			styles = append(styles, matches[2])
+		}
+	}
+
+	return styles, nil
+}
+
+type rawCell struct {
+	line   int
+	spec   []string
+	buffer strings.Builder
+}
+
+// splitCells splits the content of a table into cells, each of which starts
+// with "|" optionally preceded by a cell specifier such as "2+" or "a".
+func splitCells(content []line) []*rawCell {
+	var (
+		cells   []*rawCell
+		current *rawCell
+	)
+
+	for _, l := range content {
+		text := l.text
+		for len(text) > 0 {
+			index := strings.IndexByte(text, '|')
+			for index > 0 && text[index-1] == '\\' {
+				next := strings.IndexByte(text[index+1:], '|')
+				if next < 0 {
+					index = -1
+					break
+				}
+
+				index += next + 1
+			}
+
+			if index < 0 {
+				if current != nil {
+					current.buffer.WriteString(text)
+				}
+
+				break
+			}
+
+			before := text[:index]
+			var spec []string
+			if matches := cellSpecExp.FindStringSubmatchIndex(before); matches != nil && matches[3] > matches[2] {
+				spec = cellSpecExp.FindStringSubmatch(before)[1:]
+				before = before[:matches[2]]
+			}
+
+			if current != nil {
+				current.buffer.WriteString(before)
+			}
+
+			current = &rawCell{line: l.number, spec: spec}
+			cells = append(cells, current)
+			text = text[index+1:]
+		}
+
+		if current != nil {
+			current.buffer.WriteString("\n")
+		}
+	}
+
+	return cells
+}
+
+func (self *parser) parseTable(current line, content []line, meta *metadata) (*node, error) {
+	table := &node{kind: kindTable, line: current.number}
+
+	var (
+		styles []string
+		err    error
+	)
+
+	if cols, ok := meta.attrs.named["cols"]; ok {
+		if styles, err = parseColumns(current, cols); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, l := range content {
+			if !isBlank(l) {
+				styles = make([]string, len(splitCells([]line{l})))
+				break
+			}
+		}
+	}
+
+	if len(styles) == 0 {
+		return nil, &Error{current.number, "table has no columns"}
+	}
+
+	// Without an explicit option, the first row is a header if it is given on a
+	// single line followed by a blank line.
+	options := meta.attrs.options
+	switch {
+	case options["header"]:
+		table.header = true
+	case options["noheader"]:
+	default:
+		table.header = len(content) > 1 && !isBlank(content[0]) && isBlank(content[1])
+	}
+
+	table.footer = options["footer"]
+
+	var (
+		row   []*cell
+		width int
+	)
+
+	for _, raw := range splitCells(content) {
+		cell := &cell{line: raw.line, colspan: 1}
+		repeat := 1
+		if raw.spec != nil {
+			if len(raw.spec[3]) > 0 {
+				return nil, &Error{raw.line, "row spans in tables are not supported"}
+			}
+
+			if len(raw.spec[1]) > 0 {
+				repeat, _ = strconv.Atoi(raw.spec[1])
+			}
+
+			if len(raw.spec[2]) > 0 {
+				cell.colspan, _ = strconv.Atoi(raw.spec[2])
+			}
+
+			cell.style = raw.spec[4]
+		}
+
+		// Column styles do not apply to the cells of the header row.
+		header := table.header && len(table.rows) == 0
+		if len(cell.style) == 0 && width < len(styles) && !header {
+			cell.style = styles[width]
+		}
+
+		if cell.children, err = self.parseCell(cell, raw); err != nil {
+			return nil, err
+		}
+
+		for ; repeat > 0; repeat-- {
+			if width+cell.colspan > len(styles) {
+				return nil, &Error{raw.line, "table cell exceeds the number of columns"}
+			}
+
+			row = append(row, cell)
+			width += cell.colspan
+			if width == len(styles) {
+				table.rows = append(table.rows, row)
+				row, width = nil, 0
+			}
+		}
+	}
+
+	if len(row) > 0 {
+		return nil, &Error{row[0].line, "incomplete table row"}
+	}
+
+	return table, nil
+}
+
+// parseCell parses the content of a cell according to its style: "a" cells
+// contain blocks, "l" cells are literal and other cells contain paragraphs.
+func (self *parser) parseCell(cell *cell, raw *rawCell) ([]*node, error) {
+	text := strings.ReplaceAll(raw.buffer.String(), "\\|", "|")
+
+	var lines []line
+	for index, l := range strings.Split(strings.Trim(text, "\n"), "\n") {
+		lines = append(lines, line{strings.TrimRight(l, " \t"), raw.line + index})
+	}
+
+	switch cell.style {
+	case "a":
+		return self.parseBlocks(dedent(lines))
+	case "l":
+		return []*node{{kind: kindLiteral, line: raw.line, text: joinLines(dedent(lines))}}, nil
+	}
+
+	var (
+		children []*node
+		texts    []string
+	)
+
+	for index, l := range append(lines, line{}) {
+		if isBlank(l) {
+			if len(texts) > 0 {
+				children = append(children, &node{kind: kindParagraph, line: lines[index-len(texts)].number, text: strings.Join(texts, "\n")})
+				texts = nil
+			}
+
+			continue
+		}
+
+		texts = append(texts, strings.TrimSpace(l.text))
+	}
+
+	return children, nil
+}
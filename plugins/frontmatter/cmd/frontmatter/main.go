@@ -0,0 +1,262 @@
+// Command frontmatter rewrites the metadata of files in a source tree, for
+// example to rename keys or to convert metadata between formats. Bodies are
+// preserved byte for byte, and files without metadata are left untouched.
+//
+//	frontmatter -rename Tags=Topics -format yaml content
+//
+// Files are recognized by extension, using the same delimiters as the
+// frontmatter plugin. The paths of modified files are printed.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"foosoft.net/projects/goldsmith-components/plugins/frontmatter"
+	"gopkg.in/yaml.v2"
+)
+
+type stringList []string
+
+func (self *stringList) String() string {
+	return strings.Join(*self, ",")
+}
+
+func (self *stringList) Set(value string) error {
+	*self = append(*self, value)
+	return nil
+}
+
+type pair struct {
+	key   string
+	value string
+}
+
+type options struct {
+	format  *frontmatter.Format
+	style   frontmatter.Delimiters
+	renames []pair
+	deletes []string
+	sets    []pair
+	values  map[string]interface{}
+	dryRun  bool
+}
+
+func parseFormat(value string) (*frontmatter.Format, error) {
+	var format frontmatter.Format
+	switch strings.ToLower(value) {
+	case "":
+		return nil, nil
+	case "yaml":
+		format = frontmatter.FormatYaml
+	case "toml":
+		format = frontmatter.FormatToml
+	case "json":
+		format = frontmatter.FormatJson
+	default:
+		return nil, fmt.Errorf("unsupported format %q", value)
+	}
+
+	return &format, nil
+}
+
+func parseStyle(value string) (frontmatter.Delimiters, error) {
+	switch strings.ToLower(value) {
+	case "":
+		return 0, nil
+	case "plain":
+		return frontmatter.DelimitersPlain, nil
+	case "html":
+		return frontmatter.DelimitersHtml, nil
+	case "block":
+		return frontmatter.DelimitersBlock, nil
+	case "line":
+		return frontmatter.DelimitersLine, nil
+	}
+
+	return 0, fmt.Errorf("unsupported delimiter style %q", value)
+}
+
+func parsePairs(values []string) ([]pair, error) {
+	var pairs []pair
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 || len(parts[0]) == 0 {
+			return nil, fmt.Errorf("expected key=value, got %q", value)
+		}
+
+		pairs = append(pairs, pair{parts[0], parts[1]})
+	}
+
+	return pairs, nil
+}
+
+func rewrite(path string, opts *options) (bool, error) {
+	styles := frontmatter.DefaultDelimiters(filepath.Ext(path))
+	if styles == 0 {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	doc, err := frontmatter.Parse(path, bytes.NewReader(data), styles)
+	if err != nil {
+		return false, err
+	}
+
+	if doc.Meta == nil {
+		return false, nil
+	}
+
+	for _, rename := range opts.renames {
+		if value, ok := doc.Meta[rename.key]; ok {
+			delete(doc.Meta, rename.key)
+			doc.Meta[rename.value] = value
+		}
+	}
+
+	for _, key := range opts.deletes {
+		delete(doc.Meta, key)
+	}
+
+	for _, set := range opts.sets {
+		doc.Meta[set.key] = opts.values[set.key]
+	}
+
+	if opts.format != nil {
+		doc.Format = *opts.format
+	}
+
+	if opts.style != 0 {
+		if opts.style&styles == 0 {
+			return false, fmt.Errorf("%s: delimiter style is not supported for this file type", path)
+		}
+
+		doc.Style = opts.style
+	}
+
+	var buff bytes.Buffer
+	if _, err := doc.WriteTo(&buff); err != nil {
+		return false, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if bytes.Equal(buff.Bytes(), data) {
+		return false, nil
+	}
+
+	if opts.dryRun {
+		return true, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+
+	return true, os.WriteFile(path, buff.Bytes(), info.Mode())
+}
+
+func main() {
+	var (
+		format  = flag.String("format", "", "rewrite metadata in format: yaml, toml or json")
+		style   = flag.String("style", "", "rewrite metadata with delimiter style: plain, html, block or line")
+		dryRun  = flag.Bool("dry-run", false, "print the paths of files which would change without writing them")
+		renames stringList
+		deletes stringList
+		sets    stringList
+	)
+
+	flag.Var(&renames, "rename", "rename a key, as Old=New (repeatable)")
+	flag.Var(&deletes, "delete", "delete a key (repeatable)")
+	flag.Var(&sets, "set", "set a key to a YAML value, as Key=Value (repeatable)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [options] path...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	opts, err := buildOptions(*format, *style, *dryRun, renames, deletes, sets)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	failed := false
+	for _, root := range flag.Args() {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if info.IsDir() {
+				return nil
+			}
+
+			changed, err := rewrite(path, opts)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed = true
+			} else if changed {
+				fmt.Println(path)
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func buildOptions(format, style string, dryRun bool, renames, deletes, sets []string) (*options, error) {
+	var (
+		opts = &options{deletes: deletes, dryRun: dryRun}
+		err  error
+	)
+
+	if opts.format, err = parseFormat(format); err != nil {
+		return nil, err
+	}
+
+	if opts.style, err = parseStyle(style); err != nil {
+		return nil, err
+	}
+
+	if opts.renames, err = parsePairs(renames); err != nil {
+		return nil, err
+	}
+
+	if opts.sets, err = parsePairs(sets); err != nil {
+		return nil, err
+	}
+
+	opts.values = make(map[string]interface{})
+	for _, set := range opts.sets {
+		var value interface{}
+		if err := yaml.Unmarshal([]byte(set.value), &value); err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", set.key, err)
+		}
+
+		opts.values[set.key] = value
+	}
+
+	return opts, nil
+}
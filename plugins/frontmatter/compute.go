@@ -0,0 +1,105 @@
+package frontmatter
+
+import (
+	"path"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"foosoft.net/projects/goldsmith"
+	"foosoft.net/projects/goldsmith-components/internal/slug"
+)
+
+// Computer callback function derives the value of a prop from a file and its
+// metadata, which includes values computed before it; a nil value is ignored.
+type Computer func(file *goldsmith.File, meta map[string]interface{}) (interface{}, error)
+
+type computedProp struct {
+	key      string
+	computer Computer
+}
+
+var datePrefixExp = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})-`)
+
+// Slug computes a lowercase, hyphenated identifier from the file name, with
+// the extension and any date prefix removed: "2023-05-01-Hello World.md"
+// becomes "hello-world".
+func Slug() Computer {
+	return func(file *goldsmith.File, meta map[string]interface{}) (interface{}, error) {
+		name := strings.TrimSuffix(file.Name(), file.Ext())
+		name = datePrefixExp.ReplaceAllString(name, "")
+
+		value := slug.Make(name)
+		if len(value) == 0 {
+			return nil, nil
+		}
+
+		return value, nil
+	}
+}
+
+// DatePrefix computes a date in the local time zone from a "2006-01-02-"
+// prefix of the file name, if present.
+func DatePrefix() Computer {
+	return func(file *goldsmith.File, meta map[string]interface{}) (interface{}, error) {
+		match := datePrefixExp.FindStringSubmatch(file.Name())
+		if match == nil {
+			return nil, nil
+		}
+
+		date, err := time.ParseInLocation("2006-01-02", match[1], time.Local)
+		if err != nil {
+			return nil, nil
+		}
+
+		return date, nil
+	}
+}
+
+// Url computes the absolute URL of the file, replacing its extension with the
+// provided one (such as ".html") unless it is empty.
+func Url(ext string) Computer {
+	return func(file *goldsmith.File, meta map[string]interface{}) (interface{}, error) {
+		filePath := file.Path()
+		if len(ext) > 0 {
+			filePath = strings.TrimSuffix(filePath, file.Ext()) + ext
+		}
+
+		return path.Join("/", filePath), nil
+	}
+}
+
+// Template computes a string by executing a text/template against the
+// metadata of the file, making it possible to reference other keys:
+//
+//	frontmatter.Template("/blog/{{.Date.Year}}/{{.Slug}}/")
+//
+// Referencing a key which is not present is an error.
+func Template(src string) (Computer, error) {
+	tmpl, err := template.New("").Option("missingkey=error").Parse(src)
+	if err != nil {
+		return nil, err
+	}
+
+	computer := func(file *goldsmith.File, meta map[string]interface{}) (interface{}, error) {
+		var builder strings.Builder
+		if err := tmpl.Execute(&builder, meta); err != nil {
+			return nil, err
+		}
+
+		return builder.String(), nil
+	}
+
+	return computer, nil
+}
+
+// MustTemplate is like Template, but panics if the template cannot be parsed.
+func MustTemplate(src string) Computer {
+	computer, err := Template(src)
+	if err != nil {
+		panic(err)
+	}
+
+	return computer
+}
@@ -0,0 +1,187 @@
+package frontmatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// Document is a file split into its metadata and body, making it possible to
+// rewrite the metadata of content outside of a build. Metadata is kept exactly
+// as decoded, without validation or normalization.
+type Document struct {
+	// Meta is the decoded metadata, or nil if the file has none.
+	Meta map[string]interface{}
+	// Format is the encoding of the metadata.
+	Format Format
+	// Style is the delimiter style enclosing the metadata (default: DelimitersPlain).
+	Style Delimiters
+	// Body is the content following the metadata, byte for byte.
+	Body []byte
+}
+
+// Parse reads a document, recognizing metadata enclosed by any of the provided
+// delimiter styles; the path is only used to identify errors.
+func Parse(path string, reader io.Reader, styles Delimiters) (*Document, error) {
+	delimiter, meta, body, err := parseHeader(path, reader, styles)
+	if err != nil {
+		return nil, err
+	}
+
+	self := &Document{Meta: meta}
+	if delimiter != nil {
+		self.Format = delimiter.format
+		self.Style = delimiter.style
+	}
+
+	if self.Body, err = io.ReadAll(body); err != nil {
+		return nil, err
+	}
+
+	return self, nil
+}
+
+// WriteTo writes the metadata encoded in the document format and delimiter
+// style, followed by the unmodified body. Documents without metadata are
+// written without a metadata section.
+func (self *Document) WriteTo(writer io.Writer) (int64, error) {
+	var buff bytes.Buffer
+	if self.Meta != nil {
+		header, err := Marshal(self.Meta, self.Format, self.Style)
+		if err != nil {
+			return 0, err
+		}
+
+		buff.Write(header)
+	}
+
+	buff.Write(self.Body)
+	return buff.WriteTo(writer)
+}
+
+// Marshal encodes metadata in the provided format, enclosed by delimiters of
+// the provided style (default: DelimitersPlain).
+func Marshal(meta map[string]interface{}, format Format, style Delimiters) ([]byte, error) {
+	if style == 0 {
+		style = DelimitersPlain
+	}
+
+	var delimiter *delimiter
+	for i := range delimiters {
+		if delimiters[i].style == style && delimiters[i].format == format {
+			delimiter = &delimiters[i]
+			break
+		}
+	}
+
+	if delimiter == nil {
+		return nil, errors.New("unsupported front matter format or delimiter style")
+	}
+
+	data, err := marshal(format, encodable(meta, format).(map[string]interface{}))
+	if err != nil {
+		return nil, err
+	}
+
+	var buff bytes.Buffer
+	buff.WriteString(delimiter.opener + "\n")
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		if len(line) == 0 {
+			continue
+		}
+
+		if len(delimiter.comment) > 0 {
+			if line == "\n" {
+				line = delimiter.comment + line
+			} else {
+				line = delimiter.comment + " " + line
+			}
+		}
+
+		buff.WriteString(line)
+	}
+
+	buff.WriteString(delimiter.closer + "\n")
+	return buff.Bytes(), nil
+}
+
+func marshal(format Format, meta map[string]interface{}) ([]byte, error) {
+	switch format {
+	case FormatToml:
+		var buff bytes.Buffer
+		if err := toml.NewEncoder(&buff).Encode(meta); err != nil {
+			return nil, err
+		}
+
+		return buff.Bytes(), nil
+	case FormatYaml:
+		if len(meta) == 0 {
+			return nil, nil
+		}
+
+		return yaml.Marshal(meta)
+	case FormatJson:
+		data, err := json.MarshalIndent(meta, "", "    ")
+		if err != nil {
+			return nil, err
+		}
+
+		// The enclosing braces are written by the delimiters.
+		data = bytes.TrimPrefix(bytes.TrimSuffix(data, []byte("}")), []byte("{"))
+		data = bytes.TrimPrefix(data, []byte("\n"))
+		return data, nil
+	}
+
+	return nil, fmt.Errorf("unsupported front matter format %d", format)
+}
+
+// encodable converts the keys of nested maps decoded from YAML into strings,
+// as required by the other encoders. TOML dates and times without offsets are
+// converted into strings for other formats, which cannot represent them.
+func encodable(value interface{}, format Format) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[key] = encodable(item, format)
+		}
+
+		return result
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[fmt.Sprint(key)] = encodable(item, format)
+		}
+
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = encodable(item, format)
+		}
+
+		return result
+	case time.Time:
+		if format == FormatToml {
+			return v
+		}
+
+		switch v.Location().String() {
+		case "datetime-local":
+			return v.Format("2006-01-02T15:04:05.999999999")
+		case "date-local":
+			return v.Format("2006-01-02")
+		case "time-local":
+			return v.Format("15:04:05.999999999")
+		}
+	}
+
+	return value
+}
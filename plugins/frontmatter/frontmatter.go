@@ -36,8 +36,70 @@
 //    - "ever"
 //  --- -->
 //
+// Stylesheets, scripts and other source files can carry metadata in comments.
+// Delimiters may be enclosed in block comments, or in line comments, in which
+// case every line of the metadata section must also be commented out:
+//
+//  /* ---
+//  Bundle: "site"
+//  --- */
+//
+//  // ---
+//  // Bundle: "site"
+//  // ---
+//
+// The delimiters recognized in a file depend on its extension. By default,
+// Markdown, reStructuredText, text and HTML files accept plain and HTML comment
+// delimiters, as do SVG images; CSS files accept block comments, while
+// JavaScript and TypeScript files accept both block and line comments. Other
+// extensions can be configured with Extension.
+//
 // Normal page content immediately follows the metadata section. The metadata
-// section is stripped after processed by this plugin.
+// section is stripped after processed by this plugin, while the content is
+// passed through byte for byte, including its line endings. A UTF-8 byte order
+// mark before the opening delimiter is ignored, and lines may be of any length.
+// Metadata which cannot be parsed results in a ParseError, which identifies
+// the file and line on which the problem was found.
+//
+// Metadata can optionally be checked against a schema, built either from a Go
+// struct or from a JSON Schema document. Files containing unknown keys, values
+// of the wrong type or values outside of an enumeration cause the build to
+// fail, while missing keys are filled in with default values. Files without a
+// metadata section are not validated.
+//
+// Since each format decodes into different Go types, metadata values are
+// normalized after parsing: maps have string keys, lists are []interface{},
+// whole numbers are int, other numbers are float64, and strings containing
+// dates (such as "2006-01-02" or RFC 3339) are time.Time. Dates are parsed in
+// the same layouts as in the "schedule" and "expression" filters.
+//
+// Props can also be computed from the file and its metadata, so that values do
+// not need to be repeated across files. Built-in computers derive a slug from
+// the file name, a date from a "2006-01-02-" file name prefix and a URL from
+// the file path, while custom values can be computed with callbacks or with
+// templates referencing other keys. Computed props never replace values which
+// are already present:
+//
+//  frontmatter.New().
+//      Compute("Slug", frontmatter.Slug()).
+//      Compute("Date", frontmatter.DatePrefix()).
+//      Compute("Url", frontmatter.Url(".html"))
+//
+// Files which cannot contain metadata themselves, such as images or PDFs, can
+// have it stored in sidecar files when sidecar support is enabled. A sidecar
+// shares the name of the file it describes, with an additional ".yaml",
+// ".yml", ".toml" or ".json" extension, and contains only metadata without
+// delimiters. For example, "photo.jpg.yaml" provides metadata for "photo.jpg".
+// Sidecar metadata is merged into the props of the described file, with
+// metadata embedded in the file itself taking precedence, and the sidecar file
+// is not emitted. Files named like sidecars without a matching file, such as
+// "i18n.en.json", are ordinary files and are passed through as-is.
+//
+// Metadata can also be rewritten outside of a build: Parse splits a file into a
+// Document, which can be modified and written back in any format and delimiter
+// style with the body left intact. The frontmatter command found in the cmd
+// directory applies such rewrites, such as renaming keys or converting between
+// formats, to an entire source tree.
 package frontmatter
 
 import (
@@ -45,8 +107,12 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 
 	"foosoft.net/projects/goldsmith"
 	"foosoft.net/projects/goldsmith-components/filters/wildcard"
@@ -55,140 +121,497 @@ import (
 )
 
 // Frontmatter chainable plugin context.
-type FrontMatter struct{}
+type FrontMatter struct {
+	schema    *Schema
+	normalize bool
+	sidecars  bool
+	computed  []computedProp
+
+	extensions   map[string]Delimiters
+	filter       *wildcard.Wildcard
+	sidecarFiles map[string]*sidecarFile
+	inputFiles   []*heldFile
+	mutex        sync.Mutex
+}
+
+var defaultExtensions = map[string]Delimiters{
+	".md":       DelimitersPlain | DelimitersHtml,
+	".markdown": DelimitersPlain | DelimitersHtml,
+	".rst":      DelimitersPlain | DelimitersHtml,
+	".txt":      DelimitersPlain | DelimitersHtml,
+	".html":     DelimitersPlain | DelimitersHtml,
+	".htm":      DelimitersPlain | DelimitersHtml,
+	".svg":      DelimitersPlain | DelimitersHtml,
+	".css":      DelimitersPlain | DelimitersBlock,
+	".js":       DelimitersPlain | DelimitersBlock | DelimitersLine,
+	".ts":       DelimitersPlain | DelimitersBlock | DelimitersLine,
+}
+
+// DefaultDelimiters returns the delimiters recognized by default in files with
+// the provided extension, such as ".md", or zero if the extension is unknown.
+func DefaultDelimiters(ext string) Delimiters {
+	return defaultExtensions[strings.ToLower(ext)]
+}
 
 // New creates a new instance of the Frontmatter plugin.
 func New() *FrontMatter {
-	return new(FrontMatter)
+	extensions := make(map[string]Delimiters, len(defaultExtensions))
+	for ext, delimiters := range defaultExtensions {
+		extensions[ext] = delimiters
+	}
+
+	return &FrontMatter{
+		normalize:  true,
+		extensions: extensions,
+	}
+}
+
+// Extension sets the delimiters recognized in files with the provided
+// extension, such as ".css"; passing zero excludes the extension altogether.
+func (self *FrontMatter) Extension(ext string, delimiters Delimiters) *FrontMatter {
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+
+	ext = strings.ToLower(ext)
+	if delimiters == 0 {
+		delete(self.extensions, ext)
+	} else {
+		self.extensions[ext] = delimiters
+	}
+
+	return self
+}
+
+// Sidecars sets whether metadata is read from sidecar files (default: false).
+// When enabled, all files are held until every sidecar has been read.
+func (self *FrontMatter) Sidecars(sidecars bool) *FrontMatter {
+	self.sidecars = sidecars
+	return self
+}
+
+// Normalize sets whether metadata values are converted to canonical types (default: true).
+func (self *FrontMatter) Normalize(normalize bool) *FrontMatter {
+	self.normalize = normalize
+	return self
+}
+
+// Schema sets the schema that metadata is validated against (default: none).
+func (self *FrontMatter) Schema(schema *Schema) *FrontMatter {
+	self.schema = schema
+	return self
+}
+
+// Compute sets a prop to be derived for every file with the provided callback,
+// unless the file already has a value for it. Props are computed after
+// metadata is parsed, validated and normalized, in the order they are added.
+// With sidecars enabled, they are computed once sidecar metadata has been
+// merged, including for files described by sidecars which cannot contain
+// metadata themselves.
+func (self *FrontMatter) Compute(key string, computer Computer) *FrontMatter {
+	self.computed = append(self.computed, computedProp{key, computer})
+	return self
 }
 
 func (*FrontMatter) Name() string {
 	return "frontmatter"
 }
 
-func (*FrontMatter) Initialize(context *goldsmith.Context) error {
-	context.Filter(wildcard.New("**/*.md", "**/*.markdown", "**/*.rst", "**/*.txt", "**/*.html", "**/*.htm"))
+func (self *FrontMatter) Initialize(context *goldsmith.Context) error {
+	var wildcards []string
+	for ext := range self.extensions {
+		wildcards = append(wildcards, "**/*"+ext)
+	}
+
+	self.filter = wildcard.New(wildcards...)
+
+	if self.sidecars {
+		self.sidecarFiles = make(map[string]*sidecarFile)
+	} else {
+		context.Filter(self.filter)
+	}
+
 	return nil
 }
 
-func (*FrontMatter) Process(context *goldsmith.Context, inputFile *goldsmith.File) error {
-	meta, body, err := parse(inputFile)
+func (self *FrontMatter) Process(context *goldsmith.Context, inputFile *goldsmith.File) error {
+	if !self.sidecars {
+		outputFile, meta, err := self.extract(context, inputFile)
+		if err != nil {
+			return err
+		}
+
+		if err := self.apply(outputFile, meta); err != nil {
+			return err
+		}
+
+		context.DispatchFile(outputFile)
+		return nil
+	}
+
+	// Whether a file is a sidecar is only known once every file has been seen,
+	// so possible sidecars are held until the build is finalized.
+	if targetPath, format, ok := sidecarTarget(inputFile.Path()); ok {
+		self.mutex.Lock()
+		self.sidecarFiles[targetPath] = &sidecarFile{inputFile, format}
+		self.mutex.Unlock()
+		return nil
+	}
+
+	held, err := self.hold(context, inputFile)
 	if err != nil {
 		return err
 	}
 
+	self.mutex.Lock()
+	self.inputFiles = append(self.inputFiles, held)
+	self.mutex.Unlock()
+	return nil
+}
+
+func (self *FrontMatter) Finalize(context *goldsmith.Context) error {
+	if !self.sidecars {
+		return nil
+	}
+
+	paths := make(map[string]bool)
+	for _, held := range self.inputFiles {
+		paths[held.file.Path()] = true
+	}
+
+	for _, sidecar := range self.sidecarFiles {
+		paths[sidecar.file.Path()] = true
+	}
+
+	// Files named like sidecars which do not describe another file, such as
+	// "i18n.en.json", are ordinary files.
+	for targetPath, sidecar := range self.sidecarFiles {
+		if paths[targetPath] {
+			continue
+		}
+
+		held, err := self.hold(context, sidecar.file)
+		if err != nil {
+			return err
+		}
+
+		self.inputFiles = append(self.inputFiles, held)
+		delete(self.sidecarFiles, targetPath)
+	}
+
+	for _, held := range self.inputFiles {
+		sidecar, ok := self.sidecarFiles[held.file.Path()]
+		if ok {
+			sidecarMeta, err := parseSidecar(sidecar.file, sidecar.format)
+			if err != nil {
+				return err
+			}
+
+			// Sidecar metadata is merged before it is validated, so that only
+			// keys present in the embedded metadata take precedence over it.
+			held.meta = mergeMeta(sidecarMeta, held.meta)
+			delete(self.sidecarFiles, held.file.Path())
+		}
+
+		if ok || held.extracted {
+			if err := self.apply(held.file, held.meta); err != nil {
+				return err
+			}
+		}
+
+		context.DispatchFile(held.file)
+	}
+
+	for _, sidecar := range self.sidecarFiles {
+		context.DispatchFile(sidecar.file)
+	}
+
+	return nil
+}
+
+// hold prepares a file to be held until sidecars have been read, extracting
+// its metadata if it may contain any.
+func (self *FrontMatter) hold(context *goldsmith.Context, inputFile *goldsmith.File) (*heldFile, error) {
+	if !self.filter.Accept(inputFile) {
+		return &heldFile{file: inputFile}, nil
+	}
+
+	outputFile, meta, err := self.extract(context, inputFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &heldFile{outputFile, meta, true}, nil
+}
+
+// extract splits the metadata from a file, returning a copy of the file with
+// the metadata removed, along with the metadata as it was parsed.
+func (self *FrontMatter) extract(context *goldsmith.Context, inputFile *goldsmith.File) (*goldsmith.File, map[string]interface{}, error) {
+	delimiters := self.extensions[strings.ToLower(inputFile.Ext())]
+	meta, body, err := parse(inputFile.Path(), inputFile, delimiters)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	outputFile, err := context.CreateFileFromReader(inputFile.Path(), body)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	outputFile.CopyProps(inputFile)
+	return outputFile, meta, nil
+}
+
+// apply validates and normalizes metadata, computes props from it, and stores
+// the result in the props of the file.
+func (self *FrontMatter) apply(file *goldsmith.File, meta map[string]interface{}) error {
+	if err := self.prepare(file.Path(), meta); err != nil {
+		return err
+	}
+
+	meta, err := self.compute(file, meta)
+	if err != nil {
+		return err
+	}
+
 	for name, value := range meta {
-		outputFile.SetProp(name, value)
+		file.SetProp(name, value)
 	}
 
-	context.DispatchFile(outputFile)
 	return nil
 }
 
-func parse(reader io.Reader) (map[string]interface{}, *bytes.Buffer, error) {
-	const (
-		yamlOpener     = "---"
-		yamlCloser     = "---"
-		tomlOpener     = "+++"
-		tomlCloser     = "+++"
-		jsonOpener     = "{"
-		jsonCloser     = "}"
-		yamlOpenerHtml = "<!-- ---"
-		yamlCloserHtml = "--- -->"
-		tomlOpenerHtml = "<!-- +++"
-		tomlCloserHtml = "+++ -->"
-		jsonOpenerHtml = "<!-- {"
-		jsonCloserHtml = "} -->"
-	)
+func (self *FrontMatter) compute(file *goldsmith.File, meta map[string]interface{}) (map[string]interface{}, error) {
+	for _, computed := range self.computed {
+		if _, ok := meta[computed.key]; ok {
+			continue
+		}
+
+		if _, ok := file.Prop(computed.key); ok {
+			continue
+		}
+
+		if meta == nil {
+			meta = make(map[string]interface{})
+		}
+
+		value, err := computed.computer(file, meta)
+		if err != nil {
+			return nil, fmt.Errorf("%s: computing %s: %w", file.Path(), computed.key, err)
+		}
+
+		if value != nil {
+			meta[computed.key] = value
+		}
+	}
 
+	return meta, nil
+}
+
+func (self *FrontMatter) prepare(path string, meta map[string]interface{}) error {
+	if self.schema != nil && meta != nil {
+		if err := self.schema.Validate(path, meta); err != nil {
+			return err
+		}
+	}
+
+	if self.normalize {
+		normalizeMeta(meta)
+	}
+
+	return nil
+}
+
+// ParseError describes metadata which could not be parsed, along with the
+// line of the source file on which the problem was detected, if known.
+type ParseError struct {
+	Path    string
+	Line    int
+	Message string
+}
+
+func (self *ParseError) Error() string {
+	if self.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", self.Path, self.Line, self.Message)
+	}
+
+	return fmt.Sprintf("%s: %s", self.Path, self.Message)
+}
+
+var (
+	byteOrderMark = []byte("\xef\xbb\xbf")
+	errorLineExp  = regexp.MustCompile(`line (\d+)(?: \(last key "(?:[^"\\]|\\.)*"\))?: `)
+)
+
+// newParseError converts an error returned by a decoder into a ParseError;
+// offset is the number of source lines preceding the decoded data.
+func newParseError(path string, offset int, data []byte, err error) *ParseError {
 	var (
-		body, front bytes.Buffer
-		closer      string
+		parseError  = &ParseError{Path: path, Message: err.Error()}
+		syntaxError *json.SyntaxError
+		typeError   *json.UnmarshalTypeError
 	)
 
-	meta := make(map[string]interface{})
-	scanner := bufio.NewScanner(reader)
-	header := false
-	first := true
-
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		if first {
-			first = false
-
-			if len(closer) == 0 {
-				switch strings.TrimSpace(line) {
-				case tomlOpener:
-					header = true
-					closer = tomlCloser
-				case yamlOpener:
-					header = true
-					closer = yamlCloser
-				case jsonOpener:
-					header = true
-					closer = jsonCloser
-					front.WriteString(jsonOpener)
-				case tomlOpenerHtml:
-					header = true
-					closer = tomlCloserHtml
-				case yamlOpenerHtml:
-					header = true
-					closer = yamlCloserHtml
-				case jsonOpenerHtml:
-					header = true
-					closer = jsonCloserHtml
-					front.WriteString(jsonOpener)
-				}
-			}
-
-			if header {
-				continue
-			}
+	switch {
+	case errors.As(err, &syntaxError):
+		parseError.Line = offset + lineAtOffset(data, syntaxError.Offset)
+	case errors.As(err, &typeError):
+		parseError.Line = offset + lineAtOffset(data, typeError.Offset)
+	default:
+		if match := errorLineExp.FindStringSubmatchIndex(parseError.Message); match != nil {
+			line, _ := strconv.Atoi(parseError.Message[match[2]:match[3]])
+			parseError.Line = offset + line
+			parseError.Message = parseError.Message[:match[0]] + parseError.Message[match[1]:]
 		}
+	}
 
-		if header {
-			if strings.TrimSpace(line) == closer {
-				header = false
-				if closer == jsonCloser || closer == jsonCloserHtml {
-					front.WriteString(jsonCloser)
-				}
-			} else {
-				front.Write([]byte(line + "\n"))
-			}
-		} else {
-			body.Write([]byte(line + "\n"))
+	return parseError
+}
+
+func lineAtOffset(data []byte, offset int64) int {
+	// Decoders report the offset after the byte which caused the error.
+	if offset--; offset < 0 {
+		offset = 0
+	} else if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// Delimiters is a set of the comment styles which metadata may be enclosed in.
+type Delimiters int
+
+const (
+	// DelimitersPlain matches "---", "+++" and "{" delimiters without comments.
+	DelimitersPlain Delimiters = 1 << iota
+	// DelimitersHtml matches delimiters in HTML comments, such as "<!-- ---" and "--- -->".
+	DelimitersHtml
+	// DelimitersBlock matches delimiters in block comments, such as "/* ---" and "--- */".
+	DelimitersBlock
+	// DelimitersLine matches delimiters and metadata in line comments, such as "// ---".
+	DelimitersLine
+)
+
+type delimiter struct {
+	style   Delimiters
+	opener  string
+	closer  string
+	format  Format
+	comment string
+}
+
+var delimiters = []delimiter{
+	{DelimitersPlain, "---", "---", FormatYaml, ""},
+	{DelimitersPlain, "+++", "+++", FormatToml, ""},
+	{DelimitersPlain, "{", "}", FormatJson, ""},
+	{DelimitersHtml, "<!-- ---", "--- -->", FormatYaml, ""},
+	{DelimitersHtml, "<!-- +++", "+++ -->", FormatToml, ""},
+	{DelimitersHtml, "<!-- {", "} -->", FormatJson, ""},
+	{DelimitersBlock, "/* ---", "--- */", FormatYaml, ""},
+	{DelimitersBlock, "/* +++", "+++ */", FormatToml, ""},
+	{DelimitersBlock, "/* {", "} */", FormatJson, ""},
+	{DelimitersLine, "// ---", "// ---", FormatYaml, "//"},
+	{DelimitersLine, "// +++", "// +++", FormatToml, "//"},
+	{DelimitersLine, "// {", "// }", FormatJson, "//"},
+}
+
+func findDelimiter(opener []byte, styles Delimiters) *delimiter {
+	opener = bytes.TrimSpace(bytes.TrimPrefix(opener, byteOrderMark))
+	for i, delimiter := range delimiters {
+		if delimiter.style&styles != 0 && string(opener) == delimiter.opener {
+			return &delimiters[i]
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, nil, err
+	return nil
+}
+
+func parse(path string, reader io.Reader, styles Delimiters) (map[string]interface{}, io.Reader, error) {
+	_, meta, body, err := parseHeader(path, reader, styles)
+	return meta, body, err
+}
+
+// parseHeader splits the metadata section from the body, returning the
+// delimiter which enclosed it or nil if there is none.
+func parseHeader(path string, reader io.Reader, styles Delimiters) (*delimiter, map[string]interface{}, io.Reader, error) {
+	// Lines are read without a length limit; the first line is kept intact so
+	// that files without metadata can be passed through unchanged.
+	bufReader := bufio.NewReader(reader)
+	opener, err := bufReader.ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return nil, nil, nil, err
 	}
 
-	if header {
-		return nil, nil, errors.New("unterminated front matter block")
+	delimiter := findDelimiter(opener, styles)
+	if delimiter == nil {
+		return nil, nil, io.MultiReader(bytes.NewReader(opener), bufReader), nil
 	}
 
-	switch closer {
-	case tomlCloser, tomlCloserHtml:
-		if err := toml.Unmarshal(front.Bytes(), &meta); err != nil {
-			return nil, nil, err
+	// The JSON opener is placed on the same line as the first line of the
+	// header, so that line numbers of the decoded data are offset by one line
+	// for every format.
+	var front bytes.Buffer
+	if delimiter.format == FormatJson {
+		front.WriteString("{")
+	}
+
+	for lineNumber := 2; ; lineNumber++ {
+		line, err := bufReader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, nil, nil, err
 		}
-	case yamlCloser, yamlCloserHtml:
-		if err := yaml.Unmarshal(front.Bytes(), &meta); err != nil {
-			return nil, nil, err
+
+		if string(bytes.TrimSpace(line)) == delimiter.closer {
+			break
 		}
-	case jsonCloser, jsonCloserHtml:
-		if err := json.Unmarshal(front.Bytes(), &meta); err != nil {
-			return nil, nil, err
+
+		if err == io.EOF {
+			return nil, nil, nil, &ParseError{Path: path, Line: 1, Message: "unterminated front matter block"}
 		}
+
+		if len(delimiter.comment) > 0 && len(bytes.TrimSpace(line)) > 0 {
+			line = bytes.TrimLeft(line, " \t")
+			if !bytes.HasPrefix(line, []byte(delimiter.comment)) {
+				return nil, nil, nil, &ParseError{Path: path, Line: lineNumber, Message: "front matter line is not commented"}
+			}
+
+			line = bytes.TrimPrefix(line[len(delimiter.comment):], []byte(" "))
+		}
+
+		front.Write(line)
+	}
+
+	if delimiter.format == FormatJson {
+		front.WriteString("}")
+	}
+
+	meta := make(map[string]interface{})
+	if err := unmarshal(delimiter.format, front.Bytes(), &meta); err != nil {
+		return nil, nil, nil, newParseError(path, 1, front.Bytes(), err)
+	}
+
+	return delimiter, meta, bufReader, nil
+}
+
+// Format identifies the encoding of metadata.
+type Format int
+
+const (
+	FormatYaml Format = iota
+	FormatToml
+	FormatJson
+)
+
+func unmarshal(format Format, data []byte, meta *map[string]interface{}) error {
+	switch format {
+	case FormatToml:
+		return toml.Unmarshal(data, meta)
+	case FormatYaml:
+		return yaml.Unmarshal(data, meta)
+	case FormatJson:
+		return json.Unmarshal(data, meta)
 	}
 
-	return meta, &body, nil
+	return errors.New("unsupported front matter format")
 }
@@ -1,9 +1,16 @@
 package frontmatter
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"foosoft.net/projects/goldsmith"
+	"foosoft.net/projects/goldsmith-components/filters/wildcard"
 	"foosoft.net/projects/goldsmith-components/harness"
 	"foosoft.net/projects/goldsmith-components/plugins/layout"
 )
@@ -18,3 +25,398 @@ func Test(self *testing.T) {
 		},
 	)
 }
+
+type propWriter struct {
+	keys   []string
+	filter *wildcard.Wildcard
+}
+
+func (*propWriter) Name() string {
+	return "propWriter"
+}
+
+func (self *propWriter) Initialize(context *goldsmith.Context) error {
+	context.Filter(self.filter)
+	return nil
+}
+
+func (self *propWriter) Process(context *goldsmith.Context, inputFile *goldsmith.File) error {
+	var buff bytes.Buffer
+	if _, err := buff.ReadFrom(inputFile); err != nil {
+		return err
+	}
+
+	for _, key := range self.keys {
+		if value, ok := inputFile.Prop(key); ok {
+			fmt.Fprintf(&buff, "%s: %v\n", key, value)
+		}
+	}
+
+	outputFile, err := context.CreateFileFromReader(inputFile.Path(), &buff)
+	if err != nil {
+		return err
+	}
+
+	context.DispatchFile(outputFile)
+	return nil
+}
+
+func TestSidecar(self *testing.T) {
+	harness.ValidateCase(
+		self,
+		"sidecar",
+		func(gs *goldsmith.Goldsmith) {
+			gs.
+				Chain(New().Sidecars(true)).
+				Chain(&propWriter{[]string{"Caption", "Alt", "License"}, wildcard.New("**/*.jpg", "**/*.pdf")}).
+				Chain(layout.New())
+		},
+	)
+}
+
+func TestSidecarComputed(self *testing.T) {
+	harness.ValidateCase(
+		self,
+		"sidecar_computed",
+		func(gs *goldsmith.Goldsmith) {
+			gs.
+				Chain(New().
+					Sidecars(true).
+					Compute("Slug", Slug()).
+					Compute("Url", Url("")).
+					Compute("Heading", MustTemplate("{{.Title}} ({{.Slug}})"))).
+				Chain(&propWriter{[]string{"Slug", "Url", "Heading"}, wildcard.New("**/*.jpg", "**/*.png", "**/*.md")})
+		},
+	)
+}
+
+func TestComments(self *testing.T) {
+	harness.ValidateCase(
+		self,
+		"comments",
+		func(gs *goldsmith.Goldsmith) {
+			gs.
+				Chain(New().Extension("scss", DelimitersBlock|DelimitersLine)).
+				Chain(&propWriter{[]string{"Bundle", "Order"}, wildcard.New("**/*.css", "**/*.scss", "**/*.js", "**/*.ts", "**/*.svg")})
+		},
+	)
+}
+
+func TestComputed(self *testing.T) {
+	day := func(file *goldsmith.File, meta map[string]interface{}) (interface{}, error) {
+		if date, ok := meta["Date"].(time.Time); ok {
+			return date.Format("Monday, January 2, 2006"), nil
+		}
+
+		return nil, nil
+	}
+
+	harness.ValidateCase(
+		self,
+		"computed",
+		func(gs *goldsmith.Goldsmith) {
+			gs.
+				Chain(New().
+					Compute("Slug", Slug()).
+					Compute("Date", DatePrefix()).
+					Compute("Url", Url(".html")).
+					Compute("Day", day).
+					Compute("Heading", MustTemplate("{{.Title}} ({{.Slug}})"))).
+				Chain(&propWriter{[]string{"Slug", "Day", "Url", "Heading"}, wildcard.New("**/*.md")})
+		},
+	)
+}
+
+type page struct {
+	Title  string   `frontmatter:",required"`
+	Layout string   `default:"page" enum:"page,post"`
+	Tags   []string `frontmatter:"Tags"`
+	Weight int
+	Date   time.Time
+}
+
+func TestSchema(self *testing.T) {
+	schema, err := NewSchemaFromStruct(page{})
+	if err != nil {
+		self.Fatal(err)
+	}
+
+	harness.ValidateCase(
+		self,
+		"schema",
+		func(gs *goldsmith.Goldsmith) {
+			gs.
+				Chain(New().Schema(schema)).
+				Chain(layout.New())
+		},
+	)
+}
+
+func TestSidecarSchema(self *testing.T) {
+	schema, err := NewSchemaFromStruct(page{})
+	if err != nil {
+		self.Fatal(err)
+	}
+
+	harness.ValidateCase(
+		self,
+		"sidecar_schema",
+		func(gs *goldsmith.Goldsmith) {
+			gs.
+				Chain(New().Sidecars(true).Schema(schema)).
+				Chain(layout.New())
+		},
+	)
+}
+
+func TestSchemaErrors(self *testing.T) {
+	schema, err := NewSchemaFromStruct(page{})
+	if err != nil {
+		self.Fatal(err)
+	}
+
+	meta := map[string]interface{}{
+		"Titel":  "Typo",
+		"Tags":   "go",
+		"Layout": "article",
+		"Weight": 1.5,
+		"Date":   "yesterday",
+	}
+
+	err = schema.Validate("page.md", meta)
+
+	var schemaErrs SchemaErrors
+	if !errors.As(err, &schemaErrs) {
+		self.Fatalf("expected schema errors, got %v", err)
+	}
+
+	expected := []string{
+		`page.md: key "Date": unexpected value "yesterday" of type string (expected date)`,
+		`page.md: key "Layout": unexpected value "article" of type string (expected one of page, post)`,
+		`page.md: key "Tags": unexpected value "go" of type string (expected array of string)`,
+		`page.md: key "Title": missing required key (expected string)`,
+		`page.md: key "Weight": unexpected value 1.5 of type number (expected integer)`,
+		`page.md: key "Titel": unknown key`,
+	}
+
+	if len(schemaErrs) != len(expected) {
+		self.Fatalf("expected %d errors, got %d:\n%v", len(expected), len(schemaErrs), err)
+	}
+
+	for i, message := range expected {
+		if schemaErrs[i].Error() != message {
+			self.Errorf("expected %q, got %q", message, schemaErrs[i].Error())
+		}
+	}
+}
+
+func TestSchemaJson(self *testing.T) {
+	schema, err := NewSchemaFromJSON([]byte(`{
+		"type": "object",
+		"additionalProperties": false,
+		"required": ["Title"],
+		"properties": {
+			"Title": {"type": "string"},
+			"Layout": {"type": "string", "default": "page"},
+			"Tags": {"type": "array", "items": {"type": "string"}}
+		}
+	}`))
+
+	if err != nil {
+		self.Fatal(err)
+	}
+
+	meta := map[string]interface{}{"Title": "Example", "Tags": []interface{}{"go"}}
+	if err := schema.Validate("page.md", meta); err != nil {
+		self.Error(err)
+	}
+
+	if meta["Layout"] != "page" {
+		self.Errorf("expected default layout, got %v", meta["Layout"])
+	}
+
+	meta = map[string]interface{}{"Tags": []interface{}{"go", 1}}
+	if err := schema.Validate("page.md", meta); err == nil {
+		self.Error("expected validation errors")
+	}
+}
+
+func TestNormalize(self *testing.T) {
+	sources := map[string]string{
+		"yaml": `---
+Title: "Example"
+Date: 2023-05-01
+Weight: 3
+Ratio: 0.5
+Tags: ["go", "site"]
+Author:
+  Name: "Alex"
+  Links: [{Url: "https://example.com"}]
+---
+`,
+		"toml": `+++
+Title = "Example"
+Date = 2023-05-01
+Weight = 3
+Ratio = 0.5
+Tags = ["go", "site"]
+
+[Author]
+Name = "Alex"
+Links = [{Url = "https://example.com"}]
++++
+`,
+		"json": `{
+    "Title": "Example",
+    "Date": "2023-05-01",
+    "Weight": 3,
+    "Ratio": 0.5,
+    "Tags": ["go", "site"],
+    "Author": {"Name": "Alex", "Links": [{"Url": "https://example.com"}]}
+}
+`,
+	}
+
+	expected := map[string]interface{}{
+		"Title":  "Example",
+		"Date":   time.Date(2023, 5, 1, 0, 0, 0, 0, time.Local),
+		"Weight": 3,
+		"Ratio":  0.5,
+		"Tags":   []interface{}{"go", "site"},
+		"Author": map[string]interface{}{
+			"Name":  "Alex",
+			"Links": []interface{}{map[string]interface{}{"Url": "https://example.com"}},
+		},
+	}
+
+	for format, source := range sources {
+		meta, _, err := parse(format, strings.NewReader(source), DelimitersPlain)
+		if err != nil {
+			self.Fatalf("%s: %v", format, err)
+		}
+
+		normalizeMeta(meta)
+		if !reflect.DeepEqual(meta, expected) {
+			self.Errorf("%s: unexpected result %#v", format, meta)
+		}
+	}
+}
+
+func TestParse(self *testing.T) {
+	longLine := strings.Repeat("x", 100*1024)
+
+	cases := []struct {
+		source string
+		title  interface{}
+		body   string
+	}{
+		{"\ufeff---\nTitle: Bom\n---\nBody\n", "Bom", "Body\n"},
+		{"---\r\nTitle: Crlf\r\n---\r\nFirst\r\nSecond", "Crlf", "First\r\nSecond"},
+		{"+++\nTitle = \"Toml\"\n+++\n", "Toml", ""},
+		{"<!-- {\n\"Title\": \"Json\"\n} -->\n\n", "Json", "\n"},
+		{"---\nTitle: " + longLine + "\n---\n" + longLine, longLine, longLine},
+		{"\ufeffNo header\r\n", nil, "\ufeffNo header\r\n"},
+		{"", nil, ""},
+	}
+
+	for i, c := range cases {
+		meta, body, err := parse("page.md", strings.NewReader(c.source), DelimitersPlain|DelimitersHtml)
+		if err != nil {
+			self.Fatalf("case %d: %v", i, err)
+		}
+
+		if title := meta["Title"]; title != c.title {
+			self.Errorf("case %d: unexpected title %.20q", i, title)
+		}
+
+		var buff bytes.Buffer
+		if _, err := buff.ReadFrom(body); err != nil {
+			self.Fatalf("case %d: %v", i, err)
+		}
+
+		if buff.String() != c.body {
+			self.Errorf("case %d: unexpected body %.20q", i, buff.String())
+		}
+	}
+}
+
+func TestParseErrors(self *testing.T) {
+	cases := []struct {
+		source string
+		line   int
+	}{
+		{"---\nTitle: Page\nTags: [go\n---\n", 3},
+		{"\ufeff---\r\nTitle: Page\r\n  Bad: indent\r\n---\r\n", 3},
+		{"+++\nTitle = \"Page\"\nTags = go\n+++\n", 3},
+		{"{\n\"Title\": \"Page\",\n\"Tags\": [\"go\",]\n}\n", 3},
+		{"<!-- ---\nTitle: Page\n", 1},
+		{"// ---\n// Title: Page\nTags: [go]\n// ---\n", 3},
+	}
+
+	for i, c := range cases {
+		_, _, err := parse("page.md", strings.NewReader(c.source), DelimitersPlain|DelimitersHtml|DelimitersLine)
+
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			self.Fatalf("case %d: expected parse error, got %v", i, err)
+		}
+
+		if parseErr.Path != "page.md" || parseErr.Line != c.line {
+			self.Errorf("case %d: unexpected location in %q", i, parseErr.Error())
+		}
+	}
+}
+
+func TestDocument(self *testing.T) {
+	const source = "<!-- +++\nTitle = \"Page\"\nTags = [\"go\"]\nDate = 2023-05-01\n[Author]\nName = \"Alex\"\n+++ -->\r\nBody\r\nwithout newline"
+
+	doc, err := Parse("page.md", strings.NewReader(source), DefaultDelimiters(".md"))
+	if err != nil {
+		self.Fatal(err)
+	}
+
+	if doc.Format != FormatToml || doc.Style != DelimitersHtml {
+		self.Fatalf("unexpected format %d or style %d", doc.Format, doc.Style)
+	}
+
+	doc.Meta["Topics"] = doc.Meta["Tags"]
+	delete(doc.Meta, "Tags")
+
+	expected := map[Format]string{
+		FormatYaml: "---\nAuthor:\n  Name: Alex\nDate: \"2023-05-01\"\nTitle: Page\nTopics:\n- go\n---\n",
+		FormatToml: "+++\nDate = 2023-05-01\nTitle = \"Page\"\nTopics = [\"go\"]\n\n[Author]\n  Name = \"Alex\"\n+++\n",
+		FormatJson: "{\n    \"Author\": {\n        \"Name\": \"Alex\"\n    },\n    \"Date\": \"2023-05-01\",\n    \"Title\": \"Page\",\n    \"Topics\": [\n        \"go\"\n    ]\n}\n",
+	}
+
+	for format, header := range expected {
+		doc.Format = format
+		doc.Style = DelimitersPlain
+
+		var buff bytes.Buffer
+		if _, err := doc.WriteTo(&buff); err != nil {
+			self.Fatal(err)
+		}
+
+		if buff.String() != header+"Body\r\nwithout newline" {
+			self.Errorf("%d: unexpected result %q", format, buff.String())
+		}
+
+		reparsed, err := Parse("page.md", &buff, DelimitersPlain)
+		if err != nil {
+			self.Fatalf("%d: %v", format, err)
+		}
+
+		if len(reparsed.Meta) != len(doc.Meta) || string(reparsed.Body) != string(doc.Body) {
+			self.Errorf("%d: round trip mismatch %#v", format, reparsed.Meta)
+		}
+	}
+
+	header, err := Marshal(map[string]interface{}{"Bundle": "site"}, FormatYaml, DelimitersLine)
+	if err != nil {
+		self.Fatal(err)
+	}
+
+	if string(header) != "// ---\n// Bundle: site\n// ---\n" {
+		self.Errorf("unexpected line comment header %q", header)
+	}
+}
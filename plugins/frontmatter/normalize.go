@@ -0,0 +1,116 @@
+package frontmatter
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	"foosoft.net/projects/goldsmith-components/internal/date"
+)
+
+// normalize converts values decoded from YAML, TOML and JSON into a single
+// canonical model: maps have string keys, lists are []interface{}, whole
+// numbers are int, other numbers are float64, and dates are time.Time.
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil, bool:
+		return v
+	case string:
+		if date, ok := normalizeDate(v); ok {
+			return date
+		}
+
+		return v
+	case time.Time:
+		return normalizeTime(v)
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[key] = normalize(item)
+		}
+
+		return result
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[fmt.Sprint(key)] = normalize(item)
+		}
+
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = normalize(item)
+		}
+
+		return result
+	}
+
+	reflectValue := reflect.ValueOf(value)
+	switch reflectValue.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(reflectValue.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(reflectValue.Uint())
+	case reflect.Float32, reflect.Float64:
+		number := reflectValue.Float()
+		if number == math.Trunc(number) && math.Abs(number) < 1<<53 {
+			return int(number)
+		}
+
+		return number
+	case reflect.Slice, reflect.Array:
+		return normalize(toSlice(value))
+	case reflect.Map:
+		result := make(map[string]interface{}, reflectValue.Len())
+		for _, key := range reflectValue.MapKeys() {
+			result[fmt.Sprint(key.Interface())] = normalize(reflectValue.MapIndex(key).Interface())
+		}
+
+		return result
+	}
+
+	return value
+}
+
+func normalizeMeta(meta map[string]interface{}) {
+	for key, value := range meta {
+		meta[key] = normalize(value)
+	}
+}
+
+func normalizeDate(str string) (time.Time, bool) {
+	// Only strings which look like dates are considered, to avoid the cost of
+	// attempting to parse every string with every supported layout.
+	if len(str) < len("2006-01-02") || str[4] != '-' || str[7] != '-' {
+		return time.Time{}, false
+	}
+
+	parsed, err := date.Parse(str)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return parsed, true
+}
+
+func normalizeTime(date time.Time) time.Time {
+	// TOML dates and times without offsets are decoded into special fixed time
+	// zones; convert them to the local time zone like other formats.
+	switch date.Location().String() {
+	case "datetime-local", "date-local", "time-local":
+		return time.Date(
+			date.Year(),
+			date.Month(),
+			date.Day(),
+			date.Hour(),
+			date.Minute(),
+			date.Second(),
+			date.Nanosecond(),
+			time.Local,
+		)
+	}
+
+	return date
+}
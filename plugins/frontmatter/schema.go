@@ -0,0 +1,521 @@
+package frontmatter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"foosoft.net/projects/goldsmith-components/internal/date"
+)
+
+// Field describes the constraints placed on a single front matter key.
+type Field struct {
+	// Type is one of "string", "number", "integer", "boolean", "date",
+	// "array", "object" or "any" (default).
+	Type string
+	// Items describes the elements of "array" fields.
+	Items *Field
+	// Required fields must be present in the front matter.
+	Required bool
+	// Default is used for fields which are not present in the front matter.
+	Default interface{}
+	// Enum restricts values to one of the listed values.
+	Enum []interface{}
+}
+
+// Schema describes the keys and types allowed in front matter.
+type Schema struct {
+	fields       map[string]*Field
+	allowUnknown bool
+}
+
+// NewSchema creates an empty schema, to which fields can be added.
+func NewSchema() *Schema {
+	return &Schema{fields: make(map[string]*Field)}
+}
+
+// Field adds a field to the schema.
+func (self *Schema) Field(name string, field Field) *Schema {
+	self.fields[name] = &field
+	return self
+}
+
+// AllowUnknown sets whether keys which are not described by the schema are permitted (default: false).
+func (self *Schema) AllowUnknown(allowUnknown bool) *Schema {
+	self.allowUnknown = allowUnknown
+	return self
+}
+
+// NewSchemaFromStruct derives a schema from the fields of a Go struct. Keys
+// default to field names and can be renamed with the "frontmatter" tag, which
+// also accepts the "required" option; fields tagged with "-" are skipped.
+// Default values and enumerations are set with the "default" and "enum" tags,
+// the latter containing a comma-separated list of values.
+//
+//	type Page struct {
+//		Title  string   `frontmatter:",required"`
+//		Layout string   `default:"page" enum:"page,post"`
+//		Tags   []string `frontmatter:"Tags"`
+//	}
+func NewSchemaFromStruct(value interface{}) (*Schema, error) {
+	structType := reflect.TypeOf(value)
+	for structType != nil && structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+
+	if structType == nil || structType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("schema: expected struct, got %T", value)
+	}
+
+	schema := NewSchema()
+	for i := 0; i < structType.NumField(); i++ {
+		structField := structType.Field(i)
+		if len(structField.PkgPath) > 0 {
+			continue
+		}
+
+		name := structField.Name
+		var required bool
+
+		if tag, ok := structField.Tag.Lookup("frontmatter"); ok {
+			if tag == "-" {
+				continue
+			}
+
+			parts := strings.Split(tag, ",")
+			if len(parts[0]) > 0 {
+				name = parts[0]
+			}
+
+			for _, option := range parts[1:] {
+				switch option {
+				case "required":
+					required = true
+				default:
+					return nil, fmt.Errorf("schema: field %s: unknown option %q", structField.Name, option)
+				}
+			}
+		}
+
+		field := fieldFromType(structField.Type)
+		field.Required = required
+
+		if tag, ok := structField.Tag.Lookup("enum"); ok {
+			for _, str := range strings.Split(tag, ",") {
+				value, err := parseTagValue(field.Type, strings.TrimSpace(str))
+				if err != nil {
+					return nil, fmt.Errorf("schema: field %s: enum: %w", structField.Name, err)
+				}
+
+				field.Enum = append(field.Enum, value)
+			}
+		}
+
+		if tag, ok := structField.Tag.Lookup("default"); ok {
+			value, err := parseTagValue(field.Type, tag)
+			if err != nil {
+				return nil, fmt.Errorf("schema: field %s: default: %w", structField.Name, err)
+			}
+
+			field.Default = value
+		}
+
+		schema.fields[name] = field
+	}
+
+	return schema, nil
+}
+
+func fieldFromType(fieldType reflect.Type) *Field {
+	if fieldType == reflect.TypeOf(time.Time{}) {
+		return &Field{Type: "date"}
+	}
+
+	switch fieldType.Kind() {
+	case reflect.Ptr:
+		return fieldFromType(fieldType.Elem())
+	case reflect.String:
+		return &Field{Type: "string"}
+	case reflect.Bool:
+		return &Field{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Field{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Field{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Field{Type: "array", Items: fieldFromType(fieldType.Elem())}
+	case reflect.Map, reflect.Struct:
+		return &Field{Type: "object"}
+	}
+
+	return &Field{Type: "any"}
+}
+
+func parseTagValue(fieldType, str string) (interface{}, error) {
+	switch fieldType {
+	case "integer":
+		return strconv.ParseInt(str, 10, 64)
+	case "number":
+		return strconv.ParseFloat(str, 64)
+	case "boolean":
+		return strconv.ParseBool(str)
+	case "date":
+		return date.Parse(str)
+	case "string", "any":
+		return str, nil
+	}
+
+	return nil, fmt.Errorf("values of type %s cannot be specified in tags", fieldType)
+}
+
+type jsonSchema struct {
+	Type                 interface{}            `json:"type"`
+	Format               string                 `json:"format"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	Required             []string               `json:"required"`
+	Items                *jsonSchema            `json:"items"`
+	Enum                 []interface{}          `json:"enum"`
+	Default              interface{}            `json:"default"`
+	AdditionalProperties *bool                  `json:"additionalProperties"`
+}
+
+// NewSchemaFromJSON creates a schema from a JSON Schema document. The document
+// must describe an object; the "type", "format" (only "date" and "date-time"),
+// "properties", "required", "items", "enum", "default" and
+// "additionalProperties" keywords are supported for its properties.
+func NewSchemaFromJSON(data []byte) (*Schema, error) {
+	var document jsonSchema
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("schema: %w", err)
+	}
+
+	if document.Type != nil && document.Type != "object" {
+		return nil, fmt.Errorf("schema: expected object type, got %v", document.Type)
+	}
+
+	schema := NewSchema()
+	if document.AdditionalProperties != nil {
+		schema.allowUnknown = *document.AdditionalProperties
+	} else {
+		schema.allowUnknown = true
+	}
+
+	for name, property := range document.Properties {
+		field, err := fieldFromJSON(property)
+		if err != nil {
+			return nil, fmt.Errorf("schema: property %s: %w", name, err)
+		}
+
+		schema.fields[name] = field
+	}
+
+	for _, name := range document.Required {
+		field, ok := schema.fields[name]
+		if !ok {
+			field = &Field{Type: "any"}
+			schema.fields[name] = field
+		}
+
+		field.Required = true
+	}
+
+	return schema, nil
+}
+
+func fieldFromJSON(property *jsonSchema) (*Field, error) {
+	field := &Field{Type: "any", Enum: property.Enum, Default: property.Default}
+	if property.Type != nil {
+		fieldType, ok := property.Type.(string)
+		if !ok {
+			return nil, errors.New("type must be a string")
+		}
+
+		switch fieldType {
+		case "string":
+			if property.Format == "date" || property.Format == "date-time" {
+				fieldType = "date"
+			}
+		case "number", "integer", "boolean", "array", "object":
+		default:
+			return nil, fmt.Errorf("unsupported type %q", fieldType)
+		}
+
+		field.Type = fieldType
+	}
+
+	if property.Items != nil {
+		items, err := fieldFromJSON(property.Items)
+		if err != nil {
+			return nil, fmt.Errorf("items: %w", err)
+		}
+
+		field.Items = items
+	}
+
+	return field, nil
+}
+
+// SchemaError describes a front matter value which violates the schema.
+type SchemaError struct {
+	Path     string
+	Key      string
+	Expected string
+	Message  string
+}
+
+func (self *SchemaError) Error() string {
+	if len(self.Expected) > 0 {
+		return fmt.Sprintf("%s: key %q: %s (expected %s)", self.Path, self.Key, self.Message, self.Expected)
+	}
+
+	return fmt.Sprintf("%s: key %q: %s", self.Path, self.Key, self.Message)
+}
+
+// SchemaErrors contains all violations found in a file's front matter.
+type SchemaErrors []*SchemaError
+
+func (self SchemaErrors) Error() string {
+	var messages []string
+	for _, err := range self {
+		messages = append(messages, err.Error())
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+// Validate checks the front matter of the file at the provided path against
+// the schema, filling in default values for missing fields.
+func (self *Schema) Validate(path string, meta map[string]interface{}) error {
+	var errs SchemaErrors
+
+	var names []string
+	for name := range self.fields {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		field := self.fields[name]
+		value, ok := meta[name]
+		if !ok {
+			if field.Default != nil {
+				meta[name] = field.Default
+			} else if field.Required {
+				errs = append(errs, &SchemaError{Path: path, Key: name, Expected: field.describe(), Message: "missing required key"})
+			}
+
+			continue
+		}
+
+		errs = append(errs, field.validate(path, name, value)...)
+	}
+
+	if !self.allowUnknown {
+		var unknown []string
+		for name := range meta {
+			if _, ok := self.fields[name]; !ok {
+				unknown = append(unknown, name)
+			}
+		}
+
+		sort.Strings(unknown)
+		for _, name := range unknown {
+			errs = append(errs, &SchemaError{Path: path, Key: name, Message: "unknown key"})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (self *Field) describe() string {
+	switch {
+	case self.Type == "array" && self.Items != nil && self.Items.Type != "any":
+		return fmt.Sprintf("array of %s", self.Items.describe())
+	case len(self.Type) == 0:
+		return "any"
+	default:
+		return self.Type
+	}
+}
+
+func (self *Field) validate(path, key string, value interface{}) []*SchemaError {
+	if !self.matchesType(value) {
+		return []*SchemaError{{
+			Path:     path,
+			Key:      key,
+			Expected: self.describe(),
+			Message:  fmt.Sprintf("unexpected value %s", describeValue(value)),
+		}}
+	}
+
+	if len(self.Enum) > 0 {
+		var found bool
+		for _, option := range self.Enum {
+			if valuesEqual(option, value) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			var options []string
+			for _, option := range self.Enum {
+				options = append(options, fmt.Sprintf("%v", option))
+			}
+
+			return []*SchemaError{{
+				Path:     path,
+				Key:      key,
+				Expected: fmt.Sprintf("one of %s", strings.Join(options, ", ")),
+				Message:  fmt.Sprintf("unexpected value %s", describeValue(value)),
+			}}
+		}
+	}
+
+	if self.Type == "array" && self.Items != nil {
+		var errs []*SchemaError
+		for i, item := range toSlice(value) {
+			errs = append(errs, self.Items.validate(path, fmt.Sprintf("%s[%d]", key, i), item)...)
+		}
+
+		return errs
+	}
+
+	return nil
+}
+
+func (self *Field) matchesType(value interface{}) bool {
+	switch self.Type {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := toFloat(value)
+		return ok
+	case "integer":
+		number, ok := toFloat(value)
+		return ok && number == math.Trunc(number)
+	case "date":
+		switch v := value.(type) {
+		case time.Time:
+			return true
+		case string:
+			_, err := date.Parse(v)
+			return err == nil
+		}
+
+		return false
+	case "array":
+		return toSlice(value) != nil
+	case "object":
+		switch value.(type) {
+		case map[string]interface{}, map[interface{}]interface{}:
+			return true
+		}
+
+		return false
+	}
+
+	return true
+}
+
+func describeValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return fmt.Sprintf("%q of type string", v)
+	case nil:
+		return "null"
+	}
+
+	if number, ok := toFloat(value); ok {
+		return fmt.Sprintf("%v of type number", number)
+	}
+
+	switch value.(type) {
+	case bool:
+		return fmt.Sprintf("%v of type boolean", value)
+	case time.Time:
+		return "of type date"
+	case map[string]interface{}, map[interface{}]interface{}:
+		return "of type object"
+	}
+
+	if toSlice(value) != nil {
+		return "of type array"
+	}
+
+	return fmt.Sprintf("of type %T", value)
+}
+
+func valuesEqual(a, b interface{}) bool {
+	if numberA, ok := toFloat(a); ok {
+		numberB, ok := toFloat(b)
+		return ok && numberA == numberB
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+
+	return 0, false
+}
+
+func toSlice(value interface{}) []interface{} {
+	if items, ok := value.([]interface{}); ok {
+		return items
+	}
+
+	reflectValue := reflect.ValueOf(value)
+	if reflectValue.Kind() != reflect.Slice && reflectValue.Kind() != reflect.Array {
+		return nil
+	}
+
+	items := make([]interface{}, reflectValue.Len())
+	for i := range items {
+		items[i] = reflectValue.Index(i).Interface()
+	}
+
+	return items
+}
@@ -0,0 +1,76 @@
+package frontmatter
+
+import (
+	"bytes"
+	"io"
+	"path"
+	"strings"
+
+	"foosoft.net/projects/goldsmith"
+)
+
+type sidecarFile struct {
+	file   *goldsmith.File
+	format Format
+}
+
+type heldFile struct {
+	file      *goldsmith.File
+	meta      map[string]interface{}
+	extracted bool
+}
+
+// sidecarTarget returns the path of the file which would be described by a
+// sidecar file, or false if the path cannot name a sidecar.
+func sidecarTarget(sidecarPath string) (string, Format, bool) {
+	var format Format
+
+	ext := path.Ext(sidecarPath)
+	switch strings.ToLower(ext) {
+	case ".yaml", ".yml":
+		format = FormatYaml
+	case ".toml":
+		format = FormatToml
+	case ".json":
+		format = FormatJson
+	default:
+		return "", format, false
+	}
+
+	targetPath := strings.TrimSuffix(sidecarPath, ext)
+	if len(path.Ext(targetPath)) == 0 {
+		return "", format, false
+	}
+
+	return targetPath, format, true
+}
+
+func parseSidecar(inputFile *goldsmith.File, format Format) (map[string]interface{}, error) {
+	data, err := io.ReadAll(inputFile)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := make(map[string]interface{})
+	data = bytes.TrimPrefix(data, byteOrderMark)
+	if err := unmarshal(format, data, &meta); err != nil {
+		return nil, newParseError(inputFile.Path(), 0, data, err)
+	}
+
+	return meta, nil
+}
+
+// mergeMeta merges the metadata of a sidecar with the metadata embedded in the
+// file it describes, which takes precedence.
+func mergeMeta(sidecar, embedded map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(sidecar)+len(embedded))
+	for name, value := range sidecar {
+		merged[name] = value
+	}
+
+	for name, value := range embedded {
+		merged[name] = value
+	}
+
+	return merged
+}
@@ -0,0 +1,3 @@
+export const answer: number = 42;
+Bundle: app
+Order: 3
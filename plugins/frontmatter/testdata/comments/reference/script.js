@@ -0,0 +1,3 @@
+console.log("hello");
+Bundle: site
+Order: 2
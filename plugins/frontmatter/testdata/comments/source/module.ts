@@ -0,0 +1,5 @@
+/* +++
+Bundle = "app"
+Order = 3
++++ */
+export const answer: number = 42;
@@ -0,0 +1,2 @@
+// Plain comment
+console.log("no metadata");
@@ -0,0 +1,5 @@
+// ---
+// Bundle: site
+// Order: 2
+// ---
+console.log("hello");
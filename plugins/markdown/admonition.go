@@ -0,0 +1,266 @@
+package markdown
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// KindAdmonition is the node kind of admonitions.
+var KindAdmonition = ast.NewNodeKind("Admonition")
+
+// Admonition is a callout block, such as a note or a warning, whose children
+// contain its Markdown content.
+type Admonition struct {
+	ast.BaseBlock
+	AdmonitionType string
+	Title          string
+	depth          int
+}
+
+func (*Admonition) Kind() ast.NodeKind {
+	return KindAdmonition
+}
+
+func (self *Admonition) Dump(source []byte, level int) {
+	ast.DumpHelper(self, source, level, map[string]string{"AdmonitionType": self.AdmonitionType, "Title": self.Title}, nil)
+}
+
+// Admonitions is a goldmark extension rendering GitHub-style "> [!NOTE]"
+// blockquotes and ":::warning" containers as "<aside>" elements. Text
+// following the type, such as ":::warning Be careful", is used as the title.
+type Admonitions struct {
+	types      map[string]string
+	class      string
+	titleClass string
+}
+
+// NewAdmonitions creates a new instance of the admonitions extension, which
+// supports the "note", "tip", "important", "warning" and "caution" types.
+func NewAdmonitions() *Admonitions {
+	return &Admonitions{
+		types: map[string]string{
+			"note":      "note",
+			"tip":       "tip",
+			"important": "important",
+			"warning":   "warning",
+			"caution":   "caution",
+		},
+		class:      "admonition",
+		titleClass: "admonition-title",
+	}
+}
+
+// Types sets the supported admonition types, mapping case-insensitive type
+// names to the classes added to their elements.
+func (self *Admonitions) Types(types map[string]string) *Admonitions {
+	self.types = make(map[string]string)
+	for name, class := range types {
+		self.types[strings.ToLower(name)] = class
+	}
+
+	return self
+}
+
+// Class sets the class of admonition elements (default: "admonition").
+func (self *Admonitions) Class(class string) *Admonitions {
+	self.class = class
+	return self
+}
+
+// TitleClass sets the class of admonition title elements (default: "admonition-title").
+func (self *Admonitions) TitleClass(class string) *Admonitions {
+	self.titleClass = class
+	return self
+}
+
+func (self *Admonitions) Extend(md goldmark.Markdown) {
+	md.Parser().AddOptions(
+		parser.WithBlockParsers(util.Prioritized(&admonitionParser{self}, 650)),
+		parser.WithParagraphTransformers(util.Prioritized(&admonitionParagraphTransformer{self}, 200)),
+		parser.WithASTTransformers(util.Prioritized(new(admonitionTransformer), 100)),
+	)
+
+	md.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(&admonitionRenderer{self}, 500)))
+}
+
+func (self *Admonitions) lookup(name string) (string, bool) {
+	name = strings.ToLower(name)
+	_, ok := self.types[name]
+	return name, ok
+}
+
+var (
+	admonitionFenceExp  = regexp.MustCompile(`^(:{3,})[ \t]*(\w+)(?:[ \t]+(.*?))?[ \t]*$`)
+	admonitionCloseExp  = regexp.MustCompile(`^:{3,}[ \t]*$`)
+	admonitionMarkerExp = regexp.MustCompile(`^\[!(\w+)\](?:[ \t]+(.*?))?[ \t]*$`)
+)
+
+type admonitionParser struct {
+	admonitions *Admonitions
+}
+
+func (*admonitionParser) Trigger() []byte {
+	return []byte{':'}
+}
+
+func (self *admonitionParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 {
+		return nil, parser.NoChildren
+	}
+
+	node, ok := self.parseFence(line[pos:])
+	if !ok {
+		return nil, parser.NoChildren
+	}
+
+	reader.Advance(segment.Len() - 1)
+	return node, parser.HasChildren
+}
+
+func (self *admonitionParser) parseFence(line []byte) (*Admonition, bool) {
+	matches := admonitionFenceExp.FindSubmatch(bytes.TrimRight(line, "\r\n"))
+	if matches == nil {
+		return nil, false
+	}
+
+	name, ok := self.admonitions.lookup(string(matches[2]))
+	if !ok {
+		return nil, false
+	}
+
+	return &Admonition{AdmonitionType: name, Title: string(matches[3])}, true
+}
+
+func (self *admonitionParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	admonition := node.(*Admonition)
+	line, segment := reader.PeekLine()
+
+	// Containers may be nested, in which case closing fences are matched to
+	// the innermost container first.
+	trimmed := bytes.TrimSpace(line)
+	if _, ok := self.parseFence(trimmed); ok {
+		admonition.depth++
+	} else if admonitionCloseExp.Match(trimmed) {
+		if admonition.depth == 0 {
+			reader.Advance(segment.Len() - 1)
+			return parser.Close
+		}
+
+		admonition.depth--
+	}
+
+	return parser.Continue | parser.HasChildren
+}
+
+func (*admonitionParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (*admonitionParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (*admonitionParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+// admonitionParagraphTransformer strips "[!TYPE]" markers from the first line
+// of blockquotes before inline parsing, recording the blockquotes to be
+// replaced once the document has been parsed.
+type admonitionParagraphTransformer struct {
+	admonitions *Admonitions
+}
+
+type admonitionMarker struct {
+	blockquote *ast.Blockquote
+	admonition *Admonition
+}
+
+var admonitionMarkersKey = parser.NewContextKey()
+
+func (self *admonitionParagraphTransformer) Transform(node *ast.Paragraph, reader text.Reader, pc parser.Context) {
+	blockquote, ok := node.Parent().(*ast.Blockquote)
+	if !ok || node.PreviousSibling() != nil {
+		return
+	}
+
+	lines := node.Lines()
+	if lines.Len() == 0 {
+		return
+	}
+
+	first := lines.At(0)
+	matches := admonitionMarkerExp.FindSubmatch(bytes.TrimRight(first.Value(reader.Source()), "\r\n"))
+	if matches == nil {
+		return
+	}
+
+	name, ok := self.admonitions.lookup(string(matches[1]))
+	if !ok {
+		return
+	}
+
+	markers, _ := pc.Get(admonitionMarkersKey).([]admonitionMarker)
+	admonition := &Admonition{AdmonitionType: name, Title: string(matches[2])}
+	pc.Set(admonitionMarkersKey, append(markers, admonitionMarker{blockquote, admonition}))
+
+	if lines.Len() == 1 {
+		node.Parent().RemoveChild(node.Parent(), node)
+		return
+	}
+
+	lines.SetSliced(1, lines.Len())
+	node.SetLines(lines)
+}
+
+type admonitionTransformer struct{}
+
+func (*admonitionTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	markers, _ := pc.Get(admonitionMarkersKey).([]admonitionMarker)
+	for _, marker := range markers {
+		parent := marker.blockquote.Parent()
+		if parent == nil {
+			continue
+		}
+
+		for child := marker.blockquote.FirstChild(); child != nil; child = marker.blockquote.FirstChild() {
+			marker.admonition.AppendChild(marker.admonition, child)
+		}
+
+		parent.ReplaceChild(parent, marker.blockquote, marker.admonition)
+	}
+}
+
+type admonitionRenderer struct {
+	admonitions *Admonitions
+}
+
+func (self *admonitionRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindAdmonition, self.renderAdmonition)
+}
+
+func (self *admonitionRenderer) renderAdmonition(writer util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		writer.WriteString("</aside>\n")
+		return ast.WalkContinue, nil
+	}
+
+	admonition := node.(*Admonition)
+	classes := []string{self.admonitions.class, self.admonitions.types[admonition.AdmonitionType]}
+	writer.WriteString("<aside class=\"" + html.EscapeString(strings.Join(strings.Fields(strings.Join(classes, " ")), " ")) + "\">\n")
+
+	if len(admonition.Title) > 0 {
+		writer.WriteString("<p class=\"" + html.EscapeString(self.admonitions.titleClass) + "\">" + html.EscapeString(admonition.Title) + "</p>\n")
+	}
+
+	return ast.WalkContinue, nil
+}
@@ -0,0 +1,39 @@
+package markdown
+
+import (
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+// rewriteLinks points links to Markdown sources at the HTML files they are
+// rendered to, keeping query strings and fragments; external links are kept.
+func rewriteLinks(doc ast.Node) {
+	ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if link, ok := node.(*ast.Link); ok && entering {
+			link.Destination = []byte(rewriteLink(string(link.Destination)))
+		}
+
+		return ast.WalkContinue, nil
+	})
+}
+
+func rewriteLink(destination string) string {
+	if parsed, err := url.Parse(destination); err != nil || len(parsed.Scheme) > 0 || len(parsed.Host) > 0 {
+		return destination
+	}
+
+	linkPath, suffix := destination, ""
+	if index := strings.IndexAny(destination, "?#"); index >= 0 {
+		linkPath, suffix = destination[:index], destination[index:]
+	}
+
+	switch strings.ToLower(path.Ext(linkPath)) {
+	case ".md", ".markdown":
+		return htmlPath(linkPath) + suffix
+	}
+
+	return destination
+}
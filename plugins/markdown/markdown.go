@@ -2,40 +2,256 @@
 // processor. Note that unlike other static site generators, Markdown processing
 // does not automatically parse frontmatter; you will need to use the "frontmatter"
 // plugin to extract any metadata which may be present in your source content.
+//
+// The headings of each document are collected into a nested table of contents,
+// which is stored in the "TOC" prop for use in templates. The table of contents
+// can also be rendered as an HTML fragment, either into a prop or in place of a
+// "[TOC]" paragraph within the document.
+//
+// GitHub-style "> [!NOTE]" blockquotes and ":::warning" containers are rendered
+// as aside elements, such as <aside class="admonition warning">, with any text
+// following the type used as title. The supported types and classes are
+// configurable.
+//
+// Props such as "Summary" which contain Markdown can also be rendered, so
+// that they reach templates as HTML rather than raw strings. Props intended
+// for use within other elements, such as titles, can be rendered inline
+// without wrapping paragraphs.
+//
+// Files can change the goldmark extensions and options they are rendered with
+// through a "MarkdownOptions" map in their front matter, for example to enable
+// hard line breaks or to disallow raw HTML on a single page. A goldmark
+// instance is built for each distinct set of options on first use.
+//
+// Relative links to other Markdown files are rewritten to point to the HTML
+// files they are rendered to, so that documents which link to each other work
+// both when browsed as source and on the generated site.
+//
+// Wiki links of the form "[[Page Title]]", "[[path/to/page|label]]" or
+// "[[page#anchor]]" can optionally be enabled. Links are resolved against every
+// Markdown file processed by the plugin, matching paths relative to the linking
+// file or the source root, then "Title" props and finally slugs derived from
+// "Slug" props or file names. Resolved links point to the relative ".html"
+// output path of the target; the build fails with a list of links which are
+// broken or ambiguous.
+//
+// TeX math enclosed by "$" or "$$" can optionally be converted to MathML at
+// build time, so that pages need no client-side scripts to display formulas.
+// Only a common subset of TeX is supported; math using other commands causes
+// the build to fail with an error pointing at the offending command.
+//
+// Shortcodes can optionally be enabled to embed figures, videos and similar
+// content without writing raw HTML. A shortcode such as
+// {{< figure src="a.jpg" caption="..." >}} may appear within a paragraph or on
+// a line of its own, and a shortcode on its own line may enclose Markdown
+// content up to a matching {{< /figure >}} line. Each shortcode is rendered by
+// the Go template of the same name, defined in the ".tmpl" and ".gohtml" files
+// shared with the "layout" plugin. Templates are passed a Shortcode, which
+// embeds the file being rendered so that its props can be accessed.
 package markdown
 
 import (
 	"bytes"
+	"fmt"
+	"html/template"
+	"io"
 	"path"
+	"sort"
 	"strings"
+	"sync"
 
 	"foosoft.net/projects/goldsmith"
 	"foosoft.net/projects/goldsmith-components/filters/wildcard"
 	"github.com/yuin/goldmark"
-	"github.com/yuin/goldmark/extension"
-	"github.com/yuin/goldmark/parser"
-	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
 )
 
 // Markdown chainable context.
 type Markdown struct {
-	md goldmark.Markdown
+	md           goldmark.Markdown
+	options      *Options
+	optionsKey   string
+	instances    map[Options]goldmark.Markdown
+	optionsFiles map[Options]*goldsmith.File
+
+	tocKey         string
+	tocHtmlKey     string
+	tocPlaceholder bool
+	tocMinLevel    int
+	tocMaxLevel    int
+
+	rewriteLinks   bool
+	admonitions    *Admonitions
+	propKeys       []string
+	inlinePropKeys []string
+
+	math            bool
+	shortcodes      bool
+	shortcodeParser bool
+	helpers         template.FuncMap
+	wikiLinks       bool
+	wikiParser      bool
+
+	inputFiles    []*goldsmith.File
+	templateFiles []*goldsmith.File
+	templates     *template.Template
+	mutex         sync.Mutex
 }
 
 // New creates a new instance of the Markdown plugin.
 func New() *Markdown {
-	return NewWithGoldmark(
-		goldmark.New(
-			goldmark.WithExtensions(extension.GFM, extension.Typographer),
-			goldmark.WithParserOptions(parser.WithAutoHeadingID()),
-			goldmark.WithRendererOptions(html.WithUnsafe()),
-		),
-	)
+	options := DefaultOptions()
+	admonitions := NewAdmonitions()
+
+	self := NewWithGoldmark(options.goldmark(admonitions))
+	self.options = &options
+	self.admonitions = admonitions
+	return self
 }
 
 // New creates a new instance of the Markdown plugin with user-provided goldmark instance.
 func NewWithGoldmark(md goldmark.Markdown) *Markdown {
-	return &Markdown{md}
+	return &Markdown{
+		md:           md,
+		optionsKey:   "MarkdownOptions",
+		instances:    make(map[Options]goldmark.Markdown),
+		tocKey:       "TOC",
+		tocMinLevel:  1,
+		tocMaxLevel:  6,
+		rewriteLinks: true,
+	}
+}
+
+// OptionsKey sets the metadata key used to access the goldmark options of a
+// file (default: "MarkdownOptions"). Files specifying options are rendered by
+// a goldmark instance built from DefaultOptions with the given changes, which
+// also applies to plugins created with NewWithGoldmark.
+func (self *Markdown) OptionsKey(key string) *Markdown {
+	self.optionsKey = key
+	return self
+}
+
+// RewriteLinks sets whether relative links to Markdown files, such as
+// "../guide/setup.md#install", are rewritten to point to the rendered HTML
+// files (default: true).
+func (self *Markdown) RewriteLinks(rewriteLinks bool) *Markdown {
+	self.rewriteLinks = rewriteLinks
+	return self
+}
+
+// PropKeys sets the metadata keys of string props, such as "Summary", which are
+// rendered from Markdown to HTML (default: none).
+func (self *Markdown) PropKeys(keys ...string) *Markdown {
+	self.propKeys = keys
+	return self
+}
+
+// InlinePropKeys sets the metadata keys of string props, such as "Title", which
+// are rendered from Markdown to HTML without wrapping paragraphs (default: none).
+func (self *Markdown) InlinePropKeys(keys ...string) *Markdown {
+	self.inlinePropKeys = keys
+	return self
+}
+
+// TocKey sets the metadata key used to store the table of contents as a slice
+// of headings (default: "TOC"). An empty key disables the prop.
+func (self *Markdown) TocKey(key string) *Markdown {
+	self.tocKey = key
+	return self
+}
+
+// TocHtmlKey sets the metadata key used to store the table of contents rendered
+// as an HTML fragment (default: none).
+func (self *Markdown) TocHtmlKey(key string) *Markdown {
+	self.tocHtmlKey = key
+	return self
+}
+
+// TocPlaceholder sets whether paragraphs consisting only of "[TOC]" are
+// replaced with the table of contents rendered as HTML (default: false).
+func (self *Markdown) TocPlaceholder(placeholder bool) *Markdown {
+	self.tocPlaceholder = placeholder
+	return self
+}
+
+// TocLevels sets the range of heading levels included in the table of contents (default: 1 to 6).
+func (self *Markdown) TocLevels(minLevel, maxLevel int) *Markdown {
+	self.tocMinLevel = minLevel
+	self.tocMaxLevel = maxLevel
+	return self
+}
+
+// AdmonitionTypes sets the supported admonition types, mapping case-insensitive
+// type names to the classes added to their elements (default: "note", "tip",
+// "important", "warning" and "caution", each using its name as class).
+func (self *Markdown) AdmonitionTypes(types map[string]string) *Markdown {
+	self.admonitionExtension().Types(types)
+	return self
+}
+
+// AdmonitionClasses sets the classes of admonition elements and of their
+// titles (default: "admonition" and "admonition-title").
+func (self *Markdown) AdmonitionClasses(class, titleClass string) *Markdown {
+	self.admonitionExtension().Class(class).TitleClass(titleClass)
+	return self
+}
+
+// admonitionExtension returns the admonitions extension, adding it to
+// user-provided goldmark instances on first use.
+func (self *Markdown) admonitionExtension() *Admonitions {
+	if self.admonitions == nil {
+		self.admonitions = NewAdmonitions()
+		self.admonitions.Extend(self.md)
+	}
+
+	return self.admonitions
+}
+
+// Math sets whether TeX math enclosed by "$" (inline) or "$$" (display) is
+// rendered to MathML (default: false). Math which cannot be converted, such as
+// math using unsupported commands, causes the build to fail.
+func (self *Markdown) Math(math bool) *Markdown {
+	if math && !self.math {
+		extendMath(self.md)
+		self.math = true
+	}
+
+	return self
+}
+
+// Shortcodes sets whether "{{< name args >}}" shortcodes are rendered with the
+// Go templates of the same name (default: false). Templates are defined in
+// ".tmpl" and ".gohtml" files, which are passed on unchanged so that they
+// remain available to the "layout" plugin. When enabled, files are held until
+// every template has been read.
+func (self *Markdown) Shortcodes(shortcodes bool) *Markdown {
+	if shortcodes && !self.shortcodeParser {
+		extendShortcodes(self.md)
+		self.shortcodeParser = true
+	}
+
+	self.shortcodes = shortcodes
+	return self
+}
+
+// ShortcodeHelpers sets the function map used to lookup shortcode template helper functions.
+func (self *Markdown) ShortcodeHelpers(helpers template.FuncMap) *Markdown {
+	self.helpers = helpers
+	return self
+}
+
+// WikiLinks sets whether "[[target|label]]" links are resolved against all
+// processed pages (default: false). When enabled, files are held until every
+// page has been read.
+func (self *Markdown) WikiLinks(wikiLinks bool) *Markdown {
+	if wikiLinks && !self.wikiParser {
+		extendWikiLinks(self.md)
+		self.wikiParser = true
+	}
+
+	self.wikiLinks = wikiLinks
+	return self
 }
 
 func (*Markdown) Name() string {
@@ -43,34 +259,277 @@ func (*Markdown) Name() string {
 }
 
 func (self *Markdown) Initialize(context *goldsmith.Context) error {
-	context.Filter(wildcard.New("**/*.md", "**/*.markdown"))
+	if self.shortcodes {
+		context.Filter(wildcard.New("**/*.md", "**/*.markdown", "**/*.tmpl", "**/*.gohtml"))
+	} else {
+		context.Filter(wildcard.New("**/*.md", "**/*.markdown"))
+	}
+
+	self.optionsFiles = make(map[Options]*goldsmith.File)
 	return nil
 }
 
 func (self *Markdown) Process(context *goldsmith.Context, inputFile *goldsmith.File) error {
-	outputPath := strings.TrimSuffix(inputFile.Path(), path.Ext(inputFile.Path())) + ".html"
-	if outputFile := context.RetrieveCachedFile(outputPath, inputFile); outputFile != nil {
-		outputFile.CopyProps(inputFile)
-		context.DispatchFile(outputFile)
+	switch inputFile.Ext() {
+	case ".tmpl", ".gohtml":
+		self.mutex.Lock()
+		self.templateFiles = append(self.templateFiles, inputFile)
+		self.mutex.Unlock()
 		return nil
 	}
 
+	if self.holdFiles() {
+		self.mutex.Lock()
+		self.inputFiles = append(self.inputFiles, inputFile)
+		self.mutex.Unlock()
+		return nil
+	}
+
+	_, err := self.render(context, inputFile, nil)
+	return err
+}
+
+func (self *Markdown) Finalize(context *goldsmith.Context) error {
+	if !self.holdFiles() {
+		return nil
+	}
+
+	if self.shortcodes {
+		self.templates = template.New("").Funcs(self.helpers)
+		for _, templateFile := range self.templateFiles {
+			var buff bytes.Buffer
+			if _, err := templateFile.WriteTo(&buff); err != nil {
+				return err
+			}
+
+			if _, err := self.templates.Parse(buff.String()); err != nil {
+				return fmt.Errorf("%s: %w", templateFile.Path(), err)
+			}
+
+			if _, err := templateFile.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+
+			context.DispatchFile(templateFile)
+		}
+	}
+
+	sort.Slice(self.inputFiles, func(i, j int) bool {
+		return self.inputFiles[i].Path() < self.inputFiles[j].Path()
+	})
+
+	var (
+		index  *wikiIndex
+		broken BrokenLinks
+	)
+
+	if self.wikiLinks {
+		index = newWikiIndex(self.inputFiles)
+	}
+
+	for _, inputFile := range self.inputFiles {
+		links, err := self.render(context, inputFile, index)
+		if err != nil {
+			return err
+		}
+
+		broken = append(broken, links...)
+	}
+
+	if len(broken) > 0 {
+		return broken
+	}
+
+	return nil
+}
+
+// holdFiles checks if files must be held until all inputs have been read,
+// which is the case when their output depends on other files.
+func (self *Markdown) holdFiles() bool {
+	return self.wikiLinks || self.shortcodes
+}
+
+// render converts a file to HTML, resolving wiki links against the index if
+// provided. Since the output of held files depends on other files, it is not
+// cached.
+func (self *Markdown) render(context *goldsmith.Context, inputFile *goldsmith.File, index *wikiIndex) (BrokenLinks, error) {
+	md, optionsFile, err := self.instance(context, inputFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// Files rendered with their own options depend on them as well.
+	cacheFiles := []*goldsmith.File{inputFile}
+	if optionsFile != nil {
+		cacheFiles = append(cacheFiles, optionsFile)
+	}
+
+	outputPath := htmlPath(inputFile.Path())
+	if !self.holdFiles() {
+		if outputFile := context.RetrieveCachedFile(outputPath, cacheFiles...); outputFile != nil {
+			outputFile.CopyProps(inputFile)
+
+			// Props are not cached, so the table of contents is rebuilt from the source.
+			if len(self.tocKey) > 0 || len(self.tocHtmlKey) > 0 {
+				var dataIn bytes.Buffer
+				if _, err := dataIn.ReadFrom(inputFile); err != nil {
+					return nil, err
+				}
+
+				doc := md.Parser().Parse(text.NewReader(dataIn.Bytes()))
+				self.setTocProps(outputFile, buildToc(doc, dataIn.Bytes(), self.tocMinLevel, self.tocMaxLevel))
+			}
+
+			if _, err := self.renderProps(md, outputFile, inputFile, nil); err != nil {
+				return nil, err
+			}
+
+			context.DispatchFile(outputFile)
+			return nil, nil
+		}
+	}
+
 	var dataIn bytes.Buffer
 	if _, err := dataIn.ReadFrom(inputFile); err != nil {
-		return err
+		return nil, err
+	}
+
+	doc := md.Parser().Parse(text.NewReader(dataIn.Bytes()))
+	headings := buildToc(doc, dataIn.Bytes(), self.tocMinLevel, self.tocMaxLevel)
+
+	broken, err := self.transform(md, doc, dataIn.Bytes(), inputFile, index)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", inputFile.Path(), err)
 	}
 
 	var dataOut bytes.Buffer
-	if err := self.md.Convert(dataIn.Bytes(), &dataOut); err != nil {
-		return err
+	if err := md.Renderer().Render(&dataOut, dataIn.Bytes(), doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", inputFile.Path(), err)
+	}
+
+	data := dataOut.Bytes()
+	if self.tocPlaceholder {
+		data = bytes.ReplaceAll(data, tocPlaceholder, renderToc(headings))
 	}
 
-	outputFile, err := context.CreateFileFromReader(outputPath, &dataOut)
+	outputFile, err := context.CreateFileFromReader(outputPath, bytes.NewReader(data))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	outputFile.CopyProps(inputFile)
-	context.DispatchAndCacheFile(outputFile, inputFile)
-	return nil
+	self.setTocProps(outputFile, headings)
+
+	propsBroken, err := self.renderProps(md, outputFile, inputFile, index)
+	if err != nil {
+		return nil, err
+	}
+
+	broken = append(broken, propsBroken...)
+
+	if !self.holdFiles() {
+		context.DispatchAndCacheFile(outputFile, cacheFiles...)
+	} else {
+		context.DispatchFile(outputFile)
+	}
+
+	return broken, nil
+}
+
+// instance returns the goldmark instance used to render a file, along with a
+// file describing its options if they differ from those of the plugin.
+// Instances are built on first use and shared by files with the same options,
+// as are the files describing them, which are created once per build so that
+// the cached output of a file depends on the options it was rendered with.
+func (self *Markdown) instance(context *goldsmith.Context, inputFile *goldsmith.File) (goldmark.Markdown, *goldsmith.File, error) {
+	value, ok := inputFile.Prop(self.optionsKey)
+	if !ok || len(self.optionsKey) == 0 {
+		return self.md, nil, nil
+	}
+
+	options := DefaultOptions()
+	if err := options.apply(value); err != nil {
+		return nil, nil, fmt.Errorf("%s: %s: %w", inputFile.Path(), self.optionsKey, err)
+	}
+
+	if self.options != nil && options == *self.options {
+		return self.md, nil, nil
+	}
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	md, ok := self.instances[options]
+	if !ok {
+		md = self.newInstance(options)
+		self.instances[options] = md
+	}
+
+	optionsFile, ok := self.optionsFiles[options]
+	if !ok {
+		var err error
+		optionsFile, err = context.CreateFileFromReader(path.Join(".markdown", "options", options.String()), strings.NewReader(options.String()))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		self.optionsFiles[options] = optionsFile
+	}
+
+	return md, optionsFile, nil
+}
+
+// newInstance builds a goldmark instance with the given options, adding the
+// features enabled for the plugin.
+func (self *Markdown) newInstance(options Options) goldmark.Markdown {
+	var features []goldmark.Extender
+	if self.admonitions != nil {
+		features = append(features, self.admonitions)
+	}
+
+	md := options.goldmark(features...)
+	if self.math {
+		extendMath(md)
+	}
+
+	if self.shortcodeParser {
+		extendShortcodes(md)
+	}
+
+	if self.wikiParser {
+		extendWikiLinks(md)
+	}
+
+	return md
+}
+
+// transform resolves wiki links, rewrites links and expands shortcodes in a
+// parsed document, as enabled.
+func (self *Markdown) transform(md goldmark.Markdown, doc ast.Node, source []byte, inputFile *goldsmith.File, index *wikiIndex) (BrokenLinks, error) {
+	var broken BrokenLinks
+	if index != nil {
+		broken = resolveWikiLinks(doc, inputFile, index)
+	}
+
+	if self.rewriteLinks {
+		rewriteLinks(doc)
+	}
+
+	if self.templates != nil {
+		if err := expandShortcodes(doc, source, inputFile, self.templates, md.Renderer()); err != nil {
+			return nil, err
+		}
+	}
+
+	return broken, nil
+}
+
+func (self *Markdown) setTocProps(file *goldsmith.File, headings []*Heading) {
+	if len(self.tocKey) > 0 {
+		file.SetProp(self.tocKey, headings)
+	}
+
+	if len(self.tocHtmlKey) > 0 {
+		file.SetProp(self.tocHtmlKey, string(renderToc(headings)))
+	}
 }
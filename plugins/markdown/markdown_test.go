@@ -1,6 +1,11 @@
 package markdown
 
 import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/yuin/goldmark"
@@ -10,6 +15,8 @@ import (
 
 	"foosoft.net/projects/goldsmith"
 	"foosoft.net/projects/goldsmith-components/harness"
+	"foosoft.net/projects/goldsmith-components/plugins/frontmatter"
+	"foosoft.net/projects/goldsmith-components/plugins/layout"
 )
 
 func Test(self *testing.T) {
@@ -24,3 +31,215 @@ func Test(self *testing.T) {
 		},
 	)
 }
+
+func TestToc(self *testing.T) {
+	harness.ValidateCase(
+		self,
+		"toc",
+		func(gs *goldsmith.Goldsmith) {
+			gs.
+				Chain(frontmatter.New()).
+				Chain(New().TocLevels(2, 3).TocPlaceholder(true)).
+				Chain(layout.New())
+		},
+	)
+}
+
+func TestWiki(self *testing.T) {
+	harness.ValidateCase(
+		self,
+		"wiki",
+		func(gs *goldsmith.Goldsmith) {
+			gs.
+				Chain(frontmatter.New()).
+				Chain(New().WikiLinks(true))
+		},
+	)
+}
+
+func TestWikiBroken(self *testing.T) {
+	errs := goldsmith.Begin("testdata/wiki_broken/source").
+		Chain(frontmatter.New()).
+		Chain(New().WikiLinks(true)).
+		End(self.TempDir())
+
+	var broken BrokenLinks
+	for _, err := range errs {
+		errors.As(err, &broken)
+	}
+
+	if len(broken) != 2 {
+		self.Fatalf("expected two broken links, got %v", errs)
+	}
+
+	if broken[0].Path != "index.md" || broken[0].Target != "Missing Page" {
+		self.Errorf("unexpected broken link %v", broken[0])
+	}
+
+	if broken[1].Path != "index.md" || broken[1].Target != "Duplicate" {
+		self.Errorf("unexpected broken link %v", broken[1])
+	}
+}
+
+func TestWikiDisabled(self *testing.T) {
+	var buff bytes.Buffer
+	if err := New().WikiLinks(true).WikiLinks(false).md.Convert([]byte("See [[Page|label]] & more."), &buff); err != nil {
+		self.Fatal(err)
+	}
+
+	if html := buff.String(); html != "<p>See [[Page|label]] &amp; more.</p>\n" {
+		self.Errorf("unexpected output %q", html)
+	}
+}
+
+func TestLinks(self *testing.T) {
+	harness.ValidateCase(
+		self,
+		"links",
+		func(gs *goldsmith.Goldsmith) {
+			gs.Chain(New())
+		},
+	)
+}
+
+func TestMath(self *testing.T) {
+	harness.ValidateCase(
+		self,
+		"math",
+		func(gs *goldsmith.Goldsmith) {
+			gs.Chain(New().Math(true))
+		},
+	)
+}
+
+func TestMathErrors(self *testing.T) {
+	cases := []struct {
+		source string
+		column int
+		err    string
+	}{
+		{`x + \foo{y}`, 5, `unsupported command \foo`},
+		{`\frac{1}`, 9, `missing argument`},
+		{`x^2^3`, 4, `double superscript`},
+		{`\left( x`, 9, `missing \right`},
+	}
+
+	for _, c := range cases {
+		_, err := texToMathML(c.source, false)
+
+		var mathErr *MathError
+		if !errors.As(err, &mathErr) {
+			self.Errorf("%q: expected math error, got %v", c.source, err)
+			continue
+		}
+
+		if mathErr.Column != c.column || mathErr.Message != c.err {
+			self.Errorf("%q: unexpected error %v", c.source, err)
+		}
+	}
+}
+
+func TestShortcodes(self *testing.T) {
+	harness.ValidateCase(
+		self,
+		"shortcodes",
+		func(gs *goldsmith.Goldsmith) {
+			gs.
+				Chain(frontmatter.New()).
+				Chain(New().Shortcodes(true)).
+				Chain(layout.New())
+		},
+	)
+}
+
+func TestAdmonitions(self *testing.T) {
+	harness.ValidateCase(
+		self,
+		"admonitions",
+		func(gs *goldsmith.Goldsmith) {
+			gs.Chain(New().AdmonitionTypes(map[string]string{
+				"note":    "note",
+				"tip":     "tip",
+				"warning": "warning",
+				"Danger":  "caution",
+			}))
+		},
+	)
+}
+
+func TestProps(self *testing.T) {
+	harness.ValidateCase(
+		self,
+		"props",
+		func(gs *goldsmith.Goldsmith) {
+			gs.
+				Chain(frontmatter.New()).
+				Chain(New().PropKeys("Summary", "Count").InlinePropKeys("Title", "Tagline")).
+				Chain(layout.New())
+		},
+	)
+}
+
+func TestOptions(self *testing.T) {
+	harness.ValidateCase(
+		self,
+		"options",
+		func(gs *goldsmith.Goldsmith) {
+			gs.
+				Chain(frontmatter.New()).
+				Chain(New())
+		},
+	)
+}
+
+type optionsSetter struct {
+	options map[string]interface{}
+}
+
+func (*optionsSetter) Name() string {
+	return "optionsSetter"
+}
+
+func (self *optionsSetter) Process(context *goldsmith.Context, inputFile *goldsmith.File) error {
+	inputFile.SetProp("MarkdownOptions", self.options)
+	context.DispatchFile(inputFile)
+	return nil
+}
+
+func TestOptionsCache(self *testing.T) {
+	cacheDir := self.TempDir()
+	for _, hardWraps := range []bool{true, false, true} {
+		targetDir := self.TempDir()
+		errs := goldsmith.Begin("testdata/options_cache/source").
+			Cache(cacheDir).
+			Chain(&optionsSetter{map[string]interface{}{"HardWraps": hardWraps}}).
+			Chain(New()).
+			End(targetDir)
+
+		if len(errs) > 0 {
+			self.Fatal(errs)
+		}
+
+		data, err := os.ReadFile(filepath.Join(targetDir, "index.html"))
+		if err != nil {
+			self.Fatal(err)
+		}
+
+		if html := string(data); strings.Contains(html, "<br") != hardWraps {
+			self.Errorf("unexpected output with HardWraps %t: %q", hardWraps, html)
+		}
+	}
+}
+
+func TestOptionsErrors(self *testing.T) {
+	for _, value := range []interface{}{
+		"HardWraps",
+		map[string]interface{}{"HardWrap": true},
+		map[interface{}]interface{}{"HardWraps": "yes"},
+	} {
+		options := DefaultOptions()
+		if err := options.apply(value); err == nil {
+			self.Errorf("expected error for %v", value)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package markdown
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// KindMath is the node kind of inline math.
+var KindMath = ast.NewNodeKind("Math")
+
+// Math is TeX math enclosed by "$" or "$$" within a paragraph.
+type Math struct {
+	ast.BaseInline
+	Source  string
+	Display bool
+}
+
+func (*Math) Kind() ast.NodeKind {
+	return KindMath
+}
+
+func (self *Math) Dump(source []byte, level int) {
+	ast.DumpHelper(self, source, level, map[string]string{"Source": self.Source}, nil)
+}
+
+// KindMathBlock is the node kind of display math blocks.
+var KindMathBlock = ast.NewNodeKind("MathBlock")
+
+// MathBlock is TeX math enclosed by "$$" lines, which is set in display mode.
+type MathBlock struct {
+	ast.BaseBlock
+	closed bool
+}
+
+func (*MathBlock) Kind() ast.NodeKind {
+	return KindMathBlock
+}
+
+func (self *MathBlock) Dump(source []byte, level int) {
+	ast.DumpHelper(self, source, level, nil, nil)
+}
+
+func (*MathBlock) IsRaw() bool {
+	return true
+}
+
+func extendMath(md goldmark.Markdown) {
+	md.Parser().AddOptions(
+		parser.WithBlockParsers(util.Prioritized(new(mathBlockParser), 650)),
+		parser.WithInlineParsers(util.Prioritized(new(mathInlineParser), 150)),
+	)
+
+	md.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(new(mathRenderer), 500)))
+}
+
+type mathInlineParser struct{}
+
+func (*mathInlineParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+func (*mathInlineParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+
+	// Display math within a paragraph is enclosed by "$$".
+	if bytes.HasPrefix(line, []byte("$$")) {
+		end := bytes.Index(line[2:], []byte("$$"))
+		if end <= 0 {
+			return nil
+		}
+
+		block.Advance(end + 4)
+		return &Math{Source: string(line[2 : end+2]), Display: true}
+	}
+
+	// Inline math follows the conventions of Pandoc, so that amounts such as
+	// "$5 and $10" are not mistaken for math: the opening "$" must not be
+	// followed by a space, and the closing "$" must neither be preceded by a
+	// space nor followed by a digit.
+	if len(line) < 3 || isMathSpace(line[1]) {
+		return nil
+	}
+
+	for i := 1; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '$':
+			if isMathSpace(line[i-1]) || i+1 < len(line) && line[i+1] >= '0' && line[i+1] <= '9' {
+				return nil
+			}
+
+			block.Advance(i + 1)
+			return &Math{Source: string(line[1:i])}
+		}
+	}
+
+	return nil
+}
+
+func isMathSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+type mathBlockParser struct{}
+
+func (*mathBlockParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+func (*mathBlockParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 || !bytes.HasPrefix(line[pos:], []byte("$$")) {
+		return nil, parser.NoChildren
+	}
+
+	node := new(MathBlock)
+	rest := line[pos+2:]
+	if end := bytes.Index(rest, []byte("$$")); end >= 0 {
+		// Display math followed by text is left to the inline parser.
+		if !util.IsBlank(rest[end+2:]) {
+			return nil, parser.NoChildren
+		}
+
+		start := segment.Start + pos + 2
+		node.Lines().Append(text.NewSegment(start, start+end))
+		node.closed = true
+	} else if !util.IsBlank(rest) {
+		node.Lines().Append(text.NewSegment(segment.Start+pos+2, segment.Stop))
+	}
+
+	reader.Advance(segment.Len() - 1)
+	return node, parser.NoChildren
+}
+
+func (*mathBlockParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	mathBlock := node.(*MathBlock)
+	if mathBlock.closed {
+		return parser.Close
+	}
+
+	line, segment := reader.PeekLine()
+	if end := bytes.Index(line, []byte("$$")); end >= 0 {
+		node.Lines().Append(text.NewSegment(segment.Start, segment.Start+end))
+		reader.Advance(segment.Len() - 1)
+		mathBlock.closed = true
+		return parser.Close
+	}
+
+	node.Lines().Append(segment)
+	reader.Advance(segment.Len() - 1)
+	return parser.Continue | parser.NoChildren
+}
+
+func (*mathBlockParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (*mathBlockParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (*mathBlockParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+type mathRenderer struct{}
+
+func (self *mathRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindMath, self.renderMath)
+	reg.Register(KindMathBlock, self.renderMathBlock)
+}
+
+func (*mathRenderer) renderMath(writer util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	math := node.(*Math)
+	mathml, err := texToMathML(math.Source, math.Display)
+	if err != nil {
+		return ast.WalkStop, err
+	}
+
+	writer.WriteString(mathml)
+	return ast.WalkSkipChildren, nil
+}
+
+func (*mathRenderer) renderMathBlock(writer util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	var tex bytes.Buffer
+	lines := node.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		segment := lines.At(i)
+		tex.Write(segment.Value(source))
+	}
+
+	mathml, err := texToMathML(string(bytes.TrimSpace(tex.Bytes())), true)
+	if err != nil {
+		return ast.WalkStop, err
+	}
+
+	writer.WriteString(mathml)
+	writer.WriteString("\n")
+	return ast.WalkSkipChildren, nil
+}
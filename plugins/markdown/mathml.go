@@ -0,0 +1,765 @@
+package markdown
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// MathError describes TeX math which could not be converted to MathML.
+type MathError struct {
+	Source  string
+	Column  int
+	Message string
+}
+
+func (self *MathError) Error() string {
+	return fmt.Sprintf("math %q: column %d: %s", self.Source, self.Column, self.Message)
+}
+
+var mathIdentifiers = map[string]string{
+	"alpha": "α", "beta": "β", "gamma": "γ", "delta": "δ", "epsilon": "ϵ", "varepsilon": "ε",
+	"zeta": "ζ", "eta": "η", "theta": "θ", "vartheta": "ϑ", "iota": "ι", "kappa": "κ",
+	"lambda": "λ", "mu": "μ", "nu": "ν", "xi": "ξ", "pi": "π", "varpi": "ϖ", "rho": "ρ",
+	"varrho": "ϱ", "sigma": "σ", "varsigma": "ς", "tau": "τ", "upsilon": "υ", "phi": "ϕ",
+	"varphi": "φ", "chi": "χ", "psi": "ψ", "omega": "ω",
+	"infty": "∞", "partial": "∂", "nabla": "∇", "emptyset": "∅", "varnothing": "∅",
+	"ell": "ℓ", "hbar": "ℏ", "imath": "ı", "jmath": "ȷ", "aleph": "ℵ", "Re": "ℜ", "Im": "ℑ",
+	"wp": "℘", "prime": "′",
+}
+
+// Upright identifiers are rendered with the "normal" variant.
+var mathUprightIdentifiers = map[string]string{
+	"Gamma": "Γ", "Delta": "Δ", "Theta": "Θ", "Lambda": "Λ", "Xi": "Ξ", "Pi": "Π",
+	"Sigma": "Σ", "Upsilon": "Υ", "Phi": "Φ", "Psi": "Ψ", "Omega": "Ω",
+}
+
+var mathOperators = map[string]string{
+	"pm": "±", "mp": "∓", "times": "×", "div": "÷", "cdot": "⋅", "ast": "∗", "star": "⋆",
+	"circ": "∘", "bullet": "∙", "oplus": "⊕", "ominus": "⊖", "otimes": "⊗", "odot": "⊙",
+	"cup": "∪", "cap": "∩", "setminus": "∖", "wedge": "∧", "land": "∧", "vee": "∨", "lor": "∨",
+	"neg": "¬", "lnot": "¬",
+	"leq": "≤", "le": "≤", "geq": "≥", "ge": "≥", "neq": "≠", "ne": "≠", "approx": "≈",
+	"equiv": "≡", "sim": "∼", "simeq": "≃", "cong": "≅", "propto": "∝", "ll": "≪", "gg": "≫",
+	"in": "∈", "notin": "∉", "ni": "∋", "subset": "⊂", "supset": "⊃", "subseteq": "⊆",
+	"supseteq": "⊇", "mid": "∣", "parallel": "∥", "perp": "⊥",
+	"to": "→", "rightarrow": "→", "leftarrow": "←", "gets": "←", "leftrightarrow": "↔",
+	"Rightarrow": "⇒", "Leftarrow": "⇐", "Leftrightarrow": "⇔", "implies": "⟹", "iff": "⟺",
+	"mapsto": "↦", "uparrow": "↑", "downarrow": "↓",
+	"forall": "∀", "exists": "∃", "nexists": "∄",
+	"ldots": "…", "dots": "…", "cdots": "⋯", "vdots": "⋮", "ddots": "⋱",
+	"langle": "⟨", "rangle": "⟩", "lfloor": "⌊", "rfloor": "⌋", "lceil": "⌈", "rceil": "⌉",
+	"vert": "|", "Vert": "‖", "|": "‖",
+	"{": "{", "}": "}", "$": "$", "%": "%", "&": "&amp;", "#": "#", "_": "_",
+}
+
+// Large operators take limits below and above them in display mode, except
+// for integrals.
+var mathLargeOperators = map[string]struct {
+	symbol string
+	limits bool
+}{
+	"sum": {"∑", true}, "prod": {"∏", true}, "coprod": {"∐", true},
+	"bigcup": {"⋃", true}, "bigcap": {"⋂", true}, "bigoplus": {"⨁", true},
+	"bigotimes": {"⨂", true}, "bigvee": {"⋁", true}, "bigwedge": {"⋀", true},
+	"int": {"∫", false}, "iint": {"∬", false}, "iiint": {"∭", false}, "oint": {"∮", false},
+}
+
+// Function names are rendered upright; some take limits like large operators.
+var mathFunctions = map[string]bool{
+	"sin": false, "cos": false, "tan": false, "cot": false, "sec": false, "csc": false,
+	"arcsin": false, "arccos": false, "arctan": false, "sinh": false, "cosh": false,
+	"tanh": false, "coth": false, "log": false, "ln": false, "lg": false, "exp": false,
+	"deg": false, "dim": false, "ker": false, "arg": false, "hom": false,
+	"lim": true, "liminf": true, "limsup": true, "max": true, "min": true, "sup": true,
+	"inf": true, "det": true, "gcd": true, "Pr": true,
+}
+
+var mathSpaces = map[string]string{
+	",": "0.1667em", ":": "0.2222em", ">": "0.2222em", ";": "0.2778em", "!": "-0.1667em",
+	" ": "0.3333em", "quad": "1em", "qquad": "2em",
+}
+
+var mathAccents = map[string]struct {
+	symbol  string
+	stretch bool
+	under   bool
+}{
+	"hat": {"^", false, false}, "widehat": {"^", true, false}, "check": {"ˇ", false, false},
+	"tilde": {"~", false, false}, "widetilde": {"~", true, false}, "bar": {"¯", false, false},
+	"overline": {"‾", true, false}, "underline": {"_", true, true}, "vec": {"→", false, false},
+	"overrightarrow": {"→", true, false}, "overleftarrow": {"←", true, false},
+	"dot": {"˙", false, false}, "ddot": {"¨", false, false}, "acute": {"´", false, false},
+	"grave": {"`", false, false}, "breve": {"˘", false, false},
+	"overbrace": {"⏞", true, false}, "underbrace": {"⏟", true, true},
+}
+
+var mathVariants = map[string]string{
+	"mathrm": "normal", "mathbf": "bold", "mathit": "italic", "mathbb": "double-struck",
+	"mathcal": "script", "mathscr": "script", "mathfrak": "fraktur", "mathsf": "sans-serif",
+	"mathtt": "monospace", "boldsymbol": "bold-italic",
+}
+
+var mathEnvironments = map[string]struct {
+	open  string
+	close string
+	align string
+}{
+	"matrix": {"", "", ""}, "pmatrix": {"(", ")", ""}, "bmatrix": {"[", "]", ""},
+	"Bmatrix": {"{", "}", ""}, "vmatrix": {"|", "|", ""}, "Vmatrix": {"‖", "‖", ""},
+	"cases": {"{", "", "left left"}, "aligned": {"", "", "right left"},
+}
+
+var mathCharOperators = map[rune]string{
+	'+': "+", '-': "−", '=': "=", '<': "&lt;", '>': "&gt;", ',': ",", ';': ";", ':': ":",
+	'!': "!", '(': "(", ')': ")", '[': "[", ']': "]", '|': "|", '/': "/", '*': "∗",
+	'\'': "′", '.': ".", '?': "?", '@': "@", '"': "&quot;",
+}
+
+type texParser struct {
+	src     []rune
+	pos     int
+	offset  int
+	display bool
+	variant string
+	source  string
+}
+
+// texToMathML converts a TeX math expression to a MathML element, in display
+// mode for equations set on their own lines.
+func texToMathML(src string, display bool) (string, error) {
+	self := &texParser{src: []rune(src), display: display, source: src}
+	items, err := self.parseRow()
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case self.lookingAtCommand("right"):
+		return "", self.errorf(`unexpected \right`)
+	case self.lookingAtCommand("end"):
+		return "", self.errorf(`unexpected \end`)
+	case self.peek() == '\\':
+		return "", self.errorf("line breaks are only supported in environments")
+	case !self.atEnd():
+		return "", self.errorf("unexpected %q", string(self.peek()))
+	}
+
+	var builder strings.Builder
+	builder.WriteString(`<math xmlns="http://www.w3.org/1998/Math/MathML"`)
+	if display {
+		builder.WriteString(` display="block"`)
+	}
+
+	builder.WriteString("><semantics><mrow>")
+	builder.WriteString(strings.Join(items, ""))
+	builder.WriteString(`</mrow><annotation encoding="application/x-tex">`)
+	builder.WriteString(escapeXml(src))
+	builder.WriteString("</annotation></semantics></math>")
+	return builder.String(), nil
+}
+
+func (self *texParser) errorf(format string, args ...interface{}) error {
+	return &MathError{self.source, self.offset + self.pos + 1, fmt.Sprintf(format, args...)}
+}
+
+func (self *texParser) atEnd() bool {
+	return self.pos >= len(self.src)
+}
+
+func (self *texParser) peek() rune {
+	if self.atEnd() {
+		return 0
+	}
+
+	return self.src[self.pos]
+}
+
+func (self *texParser) remainder() string {
+	return string(self.src[self.pos:])
+}
+
+func (self *texParser) skipSpace() {
+	for !self.atEnd() && unicode.IsSpace(self.peek()) {
+		self.pos++
+	}
+}
+
+// lookingAtCommand returns whether the input continues with the provided
+// command, without consuming it.
+func (self *texParser) lookingAtCommand(name string) bool {
+	if self.peek() != '\\' {
+		return false
+	}
+
+	end := self.pos + 1 + len([]rune(name))
+	if end > len(self.src) || string(self.src[self.pos+1:end]) != name {
+		return false
+	}
+
+	return end == len(self.src) || !unicode.IsLetter(self.src[end])
+}
+
+func (self *texParser) readCommand() string {
+	self.pos++
+	start := self.pos
+	for !self.atEnd() && isAsciiLetter(self.peek()) {
+		self.pos++
+	}
+
+	if self.pos == start && !self.atEnd() {
+		self.pos++
+	}
+
+	return string(self.src[start:self.pos])
+}
+
+// readBraced reads the raw text of a braced argument, such as for \text.
+func (self *texParser) readBraced() (string, error) {
+	self.skipSpace()
+	if self.peek() != '{' {
+		return "", self.errorf("expected {")
+	}
+
+	start := self.pos + 1
+	for depth := 0; !self.atEnd(); self.pos++ {
+		switch self.peek() {
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				self.pos++
+				return string(self.src[start : self.pos-1]), nil
+			}
+		case '\\':
+			self.pos++
+		}
+	}
+
+	return "", self.errorf("missing }")
+}
+
+func (self *texParser) atTerminator() bool {
+	switch self.peek() {
+	case '}', '&':
+		return true
+	case '\\':
+		return self.pos+1 < len(self.src) && self.src[self.pos+1] == '\\' ||
+			self.lookingAtCommand("right") ||
+			self.lookingAtCommand("end")
+	}
+
+	return false
+}
+
+// parseRow parses items until the end of the input or a terminator, which is
+// not consumed.
+func (self *texParser) parseRow() ([]string, error) {
+	var items []string
+	for {
+		self.skipSpace()
+		if self.atEnd() || self.atTerminator() {
+			return items, nil
+		}
+
+		if self.lookingAtCommand("displaystyle") || self.lookingAtCommand("textstyle") {
+			display := self.readCommand() == "displaystyle"
+			rest, err := self.parseRow()
+			if err != nil {
+				return nil, err
+			}
+
+			items = append(items, fmt.Sprintf(`<mstyle displaystyle="%t">%s</mstyle>`, display, mrow(rest)))
+			return items, nil
+		}
+
+		item, err := self.parseItem()
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, item)
+	}
+}
+
+// parseItem parses an atom along with its subscript and superscript.
+func (self *texParser) parseItem() (string, error) {
+	var (
+		base   = "<mrow></mrow>"
+		limits bool
+		err    error
+	)
+
+	if c := self.peek(); c != '^' && c != '_' {
+		if base, limits, err = self.parseAtom(false); err != nil {
+			return "", err
+		}
+	}
+
+	var sub, sup string
+	for {
+		self.skipSpace()
+
+		var (
+			script *string
+			kind   string
+		)
+
+		switch self.peek() {
+		case '^':
+			script, kind = &sup, "superscript"
+		case '_':
+			script, kind = &sub, "subscript"
+		default:
+			limits = limits && self.display
+			switch {
+			case len(sub) > 0 && len(sup) > 0 && limits:
+				return "<munderover>" + base + sub + sup + "</munderover>", nil
+			case len(sub) > 0 && len(sup) > 0:
+				return "<msubsup>" + base + sub + sup + "</msubsup>", nil
+			case len(sub) > 0 && limits:
+				return "<munder>" + base + sub + "</munder>", nil
+			case len(sub) > 0:
+				return "<msub>" + base + sub + "</msub>", nil
+			case len(sup) > 0 && limits:
+				return "<mover>" + base + sup + "</mover>", nil
+			case len(sup) > 0:
+				return "<msup>" + base + sup + "</msup>", nil
+			}
+
+			return base, nil
+		}
+
+		if len(*script) > 0 {
+			return "", self.errorf("double %s", kind)
+		}
+
+		self.pos++
+		if *script, err = self.parseArg(); err != nil {
+			return "", err
+		}
+	}
+}
+
+// parseArg parses the argument of a command or script, which is either a
+// braced group or a single token.
+func (self *texParser) parseArg() (string, error) {
+	self.skipSpace()
+	if self.atEnd() || self.atTerminator() || self.peek() == '^' || self.peek() == '_' {
+		return "", self.errorf("missing argument")
+	}
+
+	arg, _, err := self.parseAtom(true)
+	return arg, err
+}
+
+func (self *texParser) parseGroup() (string, error) {
+	self.pos++
+	items, err := self.parseRow()
+	if err != nil {
+		return "", err
+	}
+
+	if self.peek() != '}' {
+		return "", self.errorf("missing }")
+	}
+
+	self.pos++
+	return mrow(items), nil
+}
+
+// parseAtom parses a single element; in a command argument, only a single
+// digit is taken from numbers.
+func (self *texParser) parseAtom(arg bool) (string, bool, error) {
+	c := self.peek()
+	switch {
+	case c == '{':
+		group, err := self.parseGroup()
+		return group, false, err
+	case c == '\\':
+		return self.parseCommand()
+	case c == '}':
+		return "", false, self.errorf("unexpected }")
+	case c == '~':
+		self.pos++
+		return `<mspace width="0.3333em"></mspace>`, false, nil
+	case unicode.IsDigit(c) || c == '.' && self.pos+1 < len(self.src) && unicode.IsDigit(self.src[self.pos+1]):
+		start := self.pos
+		for self.pos++; !arg && !self.atEnd(); self.pos++ {
+			if c := self.peek(); !unicode.IsDigit(c) && (c != '.' || self.pos+1 >= len(self.src) || !unicode.IsDigit(self.src[self.pos+1])) {
+				break
+			}
+		}
+
+		return self.element("mn", string(self.src[start:self.pos])), false, nil
+	case unicode.IsLetter(c):
+		self.pos++
+		return self.element("mi", string(c)), false, nil
+	}
+
+	if symbol, ok := mathCharOperators[c]; ok {
+		self.pos++
+		if strings.ContainsRune("()[]|", c) {
+			return `<mo stretchy="false">` + symbol + "</mo>", false, nil
+		}
+
+		return "<mo>" + symbol + "</mo>", false, nil
+	}
+
+	return "", false, self.errorf("unexpected %q", string(c))
+}
+
+func (self *texParser) element(name, content string) string {
+	if len(self.variant) > 0 {
+		return fmt.Sprintf(`<%s mathvariant="%s">%s</%s>`, name, self.variant, escapeXml(content), name)
+	}
+
+	return fmt.Sprintf("<%s>%s</%s>", name, escapeXml(content), name)
+}
+
+func (self *texParser) parseCommand() (string, bool, error) {
+	start := self.pos
+	name := self.readCommand()
+
+	if symbol, ok := mathIdentifiers[name]; ok {
+		return self.element("mi", symbol), false, nil
+	}
+
+	if symbol, ok := mathUprightIdentifiers[name]; ok {
+		return `<mi mathvariant="normal">` + symbol + "</mi>", false, nil
+	}
+
+	if symbol, ok := mathOperators[name]; ok {
+		return "<mo>" + symbol + "</mo>", false, nil
+	}
+
+	if operator, ok := mathLargeOperators[name]; ok {
+		return `<mo largeop="true" movablelimits="true">` + operator.symbol + "</mo>", operator.limits, nil
+	}
+
+	if limits, ok := mathFunctions[name]; ok {
+		return "<mi>" + name + "</mi>", limits, nil
+	}
+
+	if width, ok := mathSpaces[name]; ok {
+		return `<mspace width="` + width + `"></mspace>`, false, nil
+	}
+
+	if accent, ok := mathAccents[name]; ok {
+		arg, err := self.parseArg()
+		if err != nil {
+			return "", false, err
+		}
+
+		symbol := `<mo stretchy="false">` + accent.symbol + "</mo>"
+		if accent.stretch {
+			symbol = `<mo stretchy="true">` + accent.symbol + "</mo>"
+		}
+
+		if accent.under {
+			return `<munder accentunder="true">` + arg + symbol + "</munder>", false, nil
+		}
+
+		return `<mover accent="true">` + arg + symbol + "</mover>", false, nil
+	}
+
+	if variant, ok := mathVariants[name]; ok {
+		previous := self.variant
+		self.variant = variant
+		arg, err := self.parseArg()
+		self.variant = previous
+		return arg, false, err
+	}
+
+	switch name {
+	case "frac", "dfrac", "tfrac", "binom":
+		numerator, err := self.parseArg()
+		if err != nil {
+			return "", false, err
+		}
+
+		denominator, err := self.parseArg()
+		if err != nil {
+			return "", false, err
+		}
+
+		switch name {
+		case "dfrac":
+			return `<mstyle displaystyle="true"><mfrac>` + numerator + denominator + "</mfrac></mstyle>", false, nil
+		case "tfrac":
+			return `<mstyle displaystyle="false"><mfrac>` + numerator + denominator + "</mfrac></mstyle>", false, nil
+		case "binom":
+			return `<mrow><mo>(</mo><mfrac linethickness="0">` + numerator + denominator + "</mfrac><mo>)</mo></mrow>", false, nil
+		}
+
+		return "<mfrac>" + numerator + denominator + "</mfrac>", false, nil
+	case "sqrt":
+		return self.parseSqrt()
+	case "text", "textrm", "textit", "textbf", "mbox":
+		content, err := self.readBraced()
+		if err != nil {
+			return "", false, err
+		}
+
+		content = strings.ReplaceAll(content, "\\", "")
+		if strings.HasPrefix(content, " ") {
+			content = " " + content[1:]
+		}
+
+		if strings.HasSuffix(content, " ") {
+			content = content[:len(content)-1] + " "
+		}
+
+		switch name {
+		case "textit":
+			return `<mtext mathvariant="italic">` + escapeXml(content) + "</mtext>", false, nil
+		case "textbf":
+			return `<mtext mathvariant="bold">` + escapeXml(content) + "</mtext>", false, nil
+		}
+
+		return "<mtext>" + escapeXml(content) + "</mtext>", false, nil
+	case "operatorname":
+		content, err := self.readBraced()
+		if err != nil {
+			return "", false, err
+		}
+
+		if len([]rune(content)) == 1 {
+			return `<mi mathvariant="normal">` + escapeXml(content) + "</mi>", false, nil
+		}
+
+		return "<mi>" + escapeXml(content) + "</mi>", false, nil
+	case "left":
+		return self.parseFenced()
+	case "begin":
+		return self.parseEnvironment()
+	case "right", "end":
+		self.pos = start
+		return "", false, self.errorf(`unexpected \%s`, name)
+	}
+
+	self.pos = start
+	if len(name) == 0 {
+		return "", false, self.errorf("incomplete command")
+	}
+
+	return "", false, self.errorf(`unsupported command \%s`, name)
+}
+
+func (self *texParser) parseSqrt() (string, bool, error) {
+	self.skipSpace()
+
+	var index string
+	if self.peek() == '[' {
+		start := self.pos + 1
+		end := start
+		for depth := 0; ; end++ {
+			if end >= len(self.src) {
+				return "", false, self.errorf("missing ]")
+			}
+
+			if c := self.src[end]; c == '{' {
+				depth++
+			} else if c == '}' {
+				depth--
+			} else if c == ']' && depth == 0 {
+				break
+			}
+		}
+
+		parser := &texParser{
+			src:     self.src[start:end],
+			offset:  self.offset + start,
+			display: self.display,
+			variant: self.variant,
+			source:  self.source,
+		}
+
+		items, err := parser.parseRow()
+		if err != nil {
+			return "", false, err
+		}
+
+		if !parser.atEnd() {
+			return "", false, parser.errorf("unexpected %q", parser.remainder())
+		}
+
+		index = mrow(items)
+		self.pos = end + 1
+	}
+
+	radicand, err := self.parseArg()
+	if err != nil {
+		return "", false, err
+	}
+
+	if len(index) > 0 {
+		return "<mroot>" + radicand + index + "</mroot>", false, nil
+	}
+
+	return "<msqrt>" + radicand + "</msqrt>", false, nil
+}
+
+func (self *texParser) parseDelimiter() (string, error) {
+	self.skipSpace()
+
+	c := self.peek()
+	switch c {
+	case '(', ')', '[', ']', '|', '/':
+		self.pos++
+		return string(c), nil
+	case '<':
+		self.pos++
+		return "⟨", nil
+	case '>':
+		self.pos++
+		return "⟩", nil
+	case '.':
+		self.pos++
+		return "", nil
+	case '\\':
+		start := self.pos
+		name := self.readCommand()
+		switch name {
+		case "{", "}", "langle", "rangle", "lfloor", "rfloor", "lceil", "rceil", "vert", "Vert", "|":
+			return mathOperators[name], nil
+		}
+
+		self.pos = start
+	}
+
+	return "", self.errorf("invalid delimiter")
+}
+
+func (self *texParser) parseFenced() (string, bool, error) {
+	open, err := self.parseDelimiter()
+	if err != nil {
+		return "", false, err
+	}
+
+	items, err := self.parseRow()
+	if err != nil {
+		return "", false, err
+	}
+
+	if !self.lookingAtCommand("right") {
+		return "", false, self.errorf(`missing \right`)
+	}
+
+	self.readCommand()
+	close, err := self.parseDelimiter()
+	if err != nil {
+		return "", false, err
+	}
+
+	return fence(open, close, mrow(items)), false, nil
+}
+
+func (self *texParser) parseEnvironment() (string, bool, error) {
+	start := self.pos
+	name, err := self.readBraced()
+	if err != nil {
+		return "", false, err
+	}
+
+	environment, ok := mathEnvironments[name]
+	if !ok {
+		self.pos = start
+		return "", false, self.errorf("unsupported environment %q", name)
+	}
+
+	var (
+		rows  [][]string
+		cells []string
+	)
+
+	for {
+		items, err := self.parseRow()
+		if err != nil {
+			return "", false, err
+		}
+
+		cells = append(cells, "<mtd>"+mrow(items)+"</mtd>")
+
+		switch {
+		case self.peek() == '&':
+			self.pos++
+		case self.lookingAtCommand("end"):
+			self.readCommand()
+			end, err := self.readBraced()
+			if err != nil {
+				return "", false, err
+			}
+
+			if end != name {
+				return "", false, self.errorf(`\begin{%s} ended by \end{%s}`, name, end)
+			}
+
+			if len(cells) > 1 || cells[0] != "<mtd><mrow></mrow></mtd>" {
+				rows = append(rows, cells)
+			}
+
+			return self.table(environment.open, environment.close, environment.align, rows), false, nil
+		case self.peek() == '\\':
+			self.pos += 2
+			rows = append(rows, cells)
+			cells = nil
+		case self.atEnd():
+			return "", false, self.errorf(`missing \end{%s}`, name)
+		default:
+			return "", false, self.errorf("unexpected %q", string(self.peek()))
+		}
+	}
+}
+
+func (self *texParser) table(open, close, align string, rows [][]string) string {
+	var builder strings.Builder
+	builder.WriteString("<mtable")
+	if len(align) > 0 {
+		builder.WriteString(` columnalign="` + align + `"`)
+	}
+
+	builder.WriteString(">")
+	for _, cells := range rows {
+		builder.WriteString("<mtr>" + strings.Join(cells, "") + "</mtr>")
+	}
+
+	builder.WriteString("</mtable>")
+	return fence(open, close, builder.String())
+}
+
+func fence(open, close, content string) string {
+	if len(open) == 0 && len(close) == 0 {
+		return content
+	}
+
+	var builder strings.Builder
+	builder.WriteString("<mrow>")
+	if len(open) > 0 {
+		builder.WriteString(`<mo fence="true" stretchy="true">` + escapeXml(open) + "</mo>")
+	}
+
+	builder.WriteString(content)
+	if len(close) > 0 {
+		builder.WriteString(`<mo fence="true" stretchy="true">` + escapeXml(close) + "</mo>")
+	}
+
+	builder.WriteString("</mrow>")
+	return builder.String()
+}
+
+func mrow(items []string) string {
+	if len(items) == 1 {
+		return items[0]
+	}
+
+	return "<mrow>" + strings.Join(items, "") + "</mrow>"
+}
+
+func escapeXml(str string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;").Replace(str)
+}
+
+func isAsciiLetter(c rune) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,189 @@
+package markdown
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+// Options is a set of goldmark extensions and options, which files can change
+// from the defaults through a front matter map of option names to booleans,
+// such as {"HardWraps": true, "Typographer": false}. The name "GFM" sets all
+// of the GitHub Flavored Markdown extensions at once.
+type Options struct {
+	Table          bool
+	Strikethrough  bool
+	Linkify        bool
+	TaskList       bool
+	DefinitionList bool
+	Footnote       bool
+	Typographer    bool
+	AutoHeadingID  bool
+	Attribute      bool
+	HardWraps      bool
+	XHTML          bool
+	Unsafe         bool
+}
+
+// DefaultOptions returns the options used by New: the GitHub Flavored Markdown
+// extensions, typographic substitutions, heading IDs and raw HTML.
+func DefaultOptions() Options {
+	return Options{
+		Table:         true,
+		Strikethrough: true,
+		Linkify:       true,
+		TaskList:      true,
+		Typographer:   true,
+		AutoHeadingID: true,
+		Unsafe:        true,
+	}
+}
+
+func (self *Options) fields() []struct {
+	name  string
+	value *bool
+} {
+	return []struct {
+		name  string
+		value *bool
+	}{
+		{"Table", &self.Table},
+		{"Strikethrough", &self.Strikethrough},
+		{"Linkify", &self.Linkify},
+		{"TaskList", &self.TaskList},
+		{"DefinitionList", &self.DefinitionList},
+		{"Footnote", &self.Footnote},
+		{"Typographer", &self.Typographer},
+		{"AutoHeadingID", &self.AutoHeadingID},
+		{"Attribute", &self.Attribute},
+		{"HardWraps", &self.HardWraps},
+		{"XHTML", &self.XHTML},
+		{"Unsafe", &self.Unsafe},
+	}
+}
+
+// String lists the names of the enabled options.
+func (self Options) String() string {
+	var names []string
+	for _, field := range self.fields() {
+		if *field.value {
+			names = append(names, field.name)
+		}
+	}
+
+	return strings.Join(names, ",")
+}
+
+// set changes an option by case-insensitive name.
+func (self *Options) set(name string, enabled bool) error {
+	if strings.EqualFold(name, "GFM") {
+		self.Table = enabled
+		self.Strikethrough = enabled
+		self.Linkify = enabled
+		self.TaskList = enabled
+		return nil
+	}
+
+	for _, field := range self.fields() {
+		if strings.EqualFold(name, field.name) {
+			*field.value = enabled
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown option %q", name)
+}
+
+// apply changes options from a front matter value.
+func (self *Options) apply(value interface{}) error {
+	entries := make(map[string]interface{})
+	switch value := value.(type) {
+	case map[string]interface{}:
+		entries = value
+	case map[interface{}]interface{}:
+		for name, enabled := range value {
+			entries[fmt.Sprint(name)] = enabled
+		}
+	default:
+		return fmt.Errorf("expected map of option names to booleans")
+	}
+
+	// GFM is applied first, so that individual extensions can be changed back.
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		if strings.EqualFold(name, "GFM") {
+			names = append([]string{name}, names...)
+		} else {
+			names = append(names, name)
+		}
+	}
+
+	for _, name := range names {
+		enabled, ok := entries[name].(bool)
+		if !ok {
+			return fmt.Errorf("option %q: expected boolean", name)
+		}
+
+		if err := self.set(name, enabled); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (self Options) goldmark(features ...goldmark.Extender) goldmark.Markdown {
+	var (
+		extensions      []goldmark.Extender
+		parserOptions   []parser.Option
+		rendererOptions []renderer.Option
+	)
+
+	for _, option := range []struct {
+		enabled   bool
+		extension goldmark.Extender
+	}{
+		{self.Table, extension.Table},
+		{self.Strikethrough, extension.Strikethrough},
+		{self.Linkify, extension.Linkify},
+		{self.TaskList, extension.TaskList},
+		{self.DefinitionList, extension.DefinitionList},
+		{self.Footnote, extension.Footnote},
+		{self.Typographer, extension.Typographer},
+	} {
+		if option.enabled {
+			extensions = append(extensions, option.extension)
+		}
+	}
+
+	if self.AutoHeadingID {
+		parserOptions = append(parserOptions, parser.WithAutoHeadingID())
+	}
+
+	if self.Attribute {
+		parserOptions = append(parserOptions, parser.WithAttribute())
+	}
+
+	if self.HardWraps {
+		rendererOptions = append(rendererOptions, html.WithHardWraps())
+	}
+
+	if self.XHTML {
+		rendererOptions = append(rendererOptions, html.WithXHTML())
+	}
+
+	if self.Unsafe {
+		rendererOptions = append(rendererOptions, html.WithUnsafe())
+	}
+
+	return goldmark.New(
+		goldmark.WithExtensions(append(extensions, features...)...),
+		goldmark.WithParserOptions(parserOptions...),
+		goldmark.WithRendererOptions(rendererOptions...),
+	)
+}
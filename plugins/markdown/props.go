@@ -0,0 +1,76 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+
+	"foosoft.net/projects/goldsmith"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// renderProps renders the configured props of the input file from Markdown,
+// storing the resulting HTML in the output file.
+func (self *Markdown) renderProps(md goldmark.Markdown, outputFile, inputFile *goldsmith.File, index *wikiIndex) (BrokenLinks, error) {
+	var broken BrokenLinks
+	for _, keys := range []struct {
+		keys   []string
+		inline bool
+	}{{self.propKeys, false}, {self.inlinePropKeys, true}} {
+		for _, key := range keys.keys {
+			value, ok := inputFile.Prop(key)
+			if !ok {
+				continue
+			}
+
+			source, ok := value.(string)
+			if !ok {
+				continue
+			}
+
+			html, links, err := self.renderProp(md, []byte(source), inputFile, index, keys.inline)
+			if err != nil {
+				return nil, fmt.Errorf("%s: prop %q: %w", inputFile.Path(), key, err)
+			}
+
+			outputFile.SetProp(key, html)
+			broken = append(broken, links...)
+		}
+	}
+
+	return broken, nil
+}
+
+func (self *Markdown) renderProp(md goldmark.Markdown, source []byte, inputFile *goldsmith.File, index *wikiIndex, inline bool) (template.HTML, BrokenLinks, error) {
+	doc := md.Parser().Parse(text.NewReader(source))
+
+	broken, err := self.transform(md, doc, source, inputFile, index)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Paragraphs are replaced with text blocks, which render their content only.
+	if inline {
+		for child := doc.FirstChild(); child != nil; child = child.NextSibling() {
+			if paragraph, ok := child.(*ast.Paragraph); ok {
+				textBlock := ast.NewTextBlock()
+				textBlock.SetLines(paragraph.Lines())
+				for grandchild := paragraph.FirstChild(); grandchild != nil; grandchild = paragraph.FirstChild() {
+					textBlock.AppendChild(textBlock, grandchild)
+				}
+
+				doc.ReplaceChild(doc, paragraph, textBlock)
+				child = textBlock
+			}
+		}
+	}
+
+	var buff bytes.Buffer
+	if err := md.Renderer().Render(&buff, source, doc); err != nil {
+		return "", nil, err
+	}
+
+	return template.HTML(bytes.TrimSpace(buff.Bytes())), broken, nil
+}
@@ -0,0 +1,366 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"foosoft.net/projects/goldsmith"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Shortcode is the context passed to shortcode templates. The file being
+// rendered is embedded, so that its props are available as ".Props".
+type Shortcode struct {
+	*goldsmith.File
+
+	// Name is the name of the shortcode and of the template rendering it.
+	Name string
+	// Args contains the positional arguments, such as "a.jpg" in {{< figure "a.jpg" >}}.
+	Args []string
+	// Params contains the named arguments, such as caption="..." in {{< figure caption="..." >}}.
+	Params map[string]string
+	// Inner is the rendered Markdown content between the opening and closing
+	// tags of a block shortcode.
+	Inner template.HTML
+}
+
+// Param returns the named argument with the given key, or the positional
+// argument if the key is an index.
+func (self *Shortcode) Param(key interface{}) string {
+	switch key := key.(type) {
+	case int:
+		if key >= 0 && key < len(self.Args) {
+			return self.Args[key]
+		}
+	case string:
+		return self.Params[key]
+	}
+
+	return ""
+}
+
+// KindShortcode is the node kind of inline shortcodes.
+var KindShortcode = ast.NewNodeKind("Shortcode")
+
+// ShortcodeInline is a shortcode within a paragraph, which has no content.
+type ShortcodeInline struct {
+	ast.BaseInline
+	shortcodeTag
+	output []byte
+}
+
+func (*ShortcodeInline) Kind() ast.NodeKind {
+	return KindShortcode
+}
+
+func (self *ShortcodeInline) Dump(source []byte, level int) {
+	ast.DumpHelper(self, source, level, map[string]string{"Name": self.name}, nil)
+}
+
+// KindShortcodeBlock is the node kind of block shortcodes.
+var KindShortcodeBlock = ast.NewNodeKind("ShortcodeBlock")
+
+// ShortcodeBlock is a shortcode on a line of its own; its children contain the
+// Markdown content up to the closing tag, if there is one.
+type ShortcodeBlock struct {
+	ast.BaseBlock
+	shortcodeTag
+	output []byte
+	closed bool
+	depth  int
+}
+
+func (*ShortcodeBlock) Kind() ast.NodeKind {
+	return KindShortcodeBlock
+}
+
+func (self *ShortcodeBlock) Dump(source []byte, level int) {
+	ast.DumpHelper(self, source, level, map[string]string{"Name": self.name}, nil)
+}
+
+type shortcodeTag struct {
+	name    string
+	args    []string
+	params  map[string]string
+	closing bool
+}
+
+var shortcodeTagExp = regexp.MustCompile(`^\{\{<\s*(/?)\s*([\w.-]+)((?:\s+(?:[^>"]|"(?:[^"\\]|\\.)*")*?)?)\s*>\}\}`)
+
+// parseShortcodeTag parses a tag at the start of the line, returning its length.
+func parseShortcodeTag(line []byte) (*shortcodeTag, int) {
+	matches := shortcodeTagExp.FindSubmatchIndex(line)
+	if matches == nil {
+		return nil, 0
+	}
+
+	tag := &shortcodeTag{
+		name:    string(line[matches[4]:matches[5]]),
+		params:  make(map[string]string),
+		closing: matches[3] > matches[2],
+	}
+
+	args := line[matches[6]:matches[7]]
+	if tag.closing && len(bytes.TrimSpace(args)) > 0 {
+		return nil, 0
+	}
+
+	if !tag.parseArgs(string(args)) {
+		return nil, 0
+	}
+
+	return tag, matches[1]
+}
+
+// parseArgs parses space-separated arguments, which are either positional or
+// of the form key=value, where values may be quoted with Go string syntax.
+func (self *shortcodeTag) parseArgs(args string) bool {
+	for {
+		start := 0
+		for start < len(args) && unicode.IsSpace(rune(args[start])) {
+			start++
+		}
+
+		if args = args[start:]; len(args) == 0 {
+			return true
+		}
+
+		var key string
+		if end := strings.IndexAny(args, "= \t\""); end > 0 && args[end] == '=' {
+			key, args = args[:end], args[end+1:]
+		}
+
+		var value string
+		if len(args) > 0 && args[0] == '"' {
+			quoted, err := strconv.QuotedPrefix(args)
+			if err != nil {
+				return false
+			}
+
+			if value, err = strconv.Unquote(quoted); err != nil {
+				return false
+			}
+
+			args = args[len(quoted):]
+		} else {
+			end := strings.IndexAny(args, " \t\"")
+			if end < 0 {
+				end = len(args)
+			}
+
+			value, args = args[:end], args[end:]
+		}
+
+		if len(key) > 0 {
+			self.params[key] = value
+		} else {
+			self.args = append(self.args, value)
+		}
+	}
+}
+
+func extendShortcodes(md goldmark.Markdown) {
+	md.Parser().AddOptions(
+		parser.WithBlockParsers(util.Prioritized(new(shortcodeBlockParser), 600)),
+		parser.WithInlineParsers(util.Prioritized(new(shortcodeInlineParser), 150)),
+	)
+
+	md.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(new(shortcodeRenderer), 500)))
+}
+
+type shortcodeInlineParser struct{}
+
+func (*shortcodeInlineParser) Trigger() []byte {
+	return []byte{'{'}
+}
+
+func (*shortcodeInlineParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	tag, length := parseShortcodeTag(line)
+	if tag == nil || tag.closing {
+		return nil
+	}
+
+	block.Advance(length)
+	return &ShortcodeInline{shortcodeTag: *tag}
+}
+
+type shortcodeBlockParser struct{}
+
+func (*shortcodeBlockParser) Trigger() []byte {
+	return []byte{'{'}
+}
+
+func (*shortcodeBlockParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 {
+		return nil, parser.NoChildren
+	}
+
+	// Tags which share their line with other text are left to the inline parser.
+	tag, length := parseShortcodeTag(line[pos:])
+	if tag == nil || tag.closing || !util.IsBlank(line[pos+length:]) {
+		return nil, parser.NoChildren
+	}
+
+	node := &ShortcodeBlock{shortcodeTag: *tag}
+	reader.Advance(segment.Len() - 1)
+
+	// Only shortcodes which are closed later on contain content.
+	if !hasClosingTag(reader.Source()[segment.Stop:], tag.name) {
+		node.closed = true
+		return node, parser.NoChildren
+	}
+
+	return node, parser.HasChildren
+}
+
+func (*shortcodeBlockParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	shortcode := node.(*ShortcodeBlock)
+	if shortcode.closed {
+		return parser.Close
+	}
+
+	// Shortcodes of the same name may be nested, in which case closing tags
+	// are matched to the innermost shortcode first.
+	line, segment := reader.PeekLine()
+	line = bytes.TrimSpace(line)
+	if tag, length := parseShortcodeTag(line); tag != nil && tag.name == shortcode.name && length == len(line) {
+		switch {
+		case !tag.closing:
+			shortcode.depth++
+		case shortcode.depth > 0:
+			shortcode.depth--
+		default:
+			reader.Advance(segment.Len() - 1)
+			shortcode.closed = true
+			return parser.Close
+		}
+	}
+
+	return parser.Continue | parser.HasChildren
+}
+
+func (*shortcodeBlockParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (*shortcodeBlockParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (*shortcodeBlockParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+// hasClosingTag checks if the source contains a line closing the shortcode,
+// accounting for shortcodes of the same name nested within it.
+func hasClosingTag(source []byte, name string) bool {
+	depth := 1
+	for _, line := range bytes.Split(source, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if tag, length := parseShortcodeTag(line); tag != nil && tag.name == name && length == len(line) {
+			if !tag.closing {
+				depth++
+			} else if depth--; depth == 0 {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+type shortcodeRenderer struct{}
+
+func (self *shortcodeRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindShortcode, self.renderShortcode)
+	reg.Register(KindShortcodeBlock, self.renderShortcode)
+}
+
+func (*shortcodeRenderer) renderShortcode(writer util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	switch node := node.(type) {
+	case *ShortcodeInline:
+		writer.Write(node.output)
+	case *ShortcodeBlock:
+		writer.Write(node.output)
+		if len(node.output) > 0 && node.output[len(node.output)-1] != '\n' {
+			writer.WriteByte('\n')
+		}
+	}
+
+	return ast.WalkSkipChildren, nil
+}
+
+// expandShortcodes executes the templates of all shortcodes in a document,
+// innermost first, so that the content of block shortcodes is rendered before
+// being passed to the template of the shortcode containing it.
+func expandShortcodes(doc ast.Node, source []byte, file *goldsmith.File, templates *template.Template, render renderer.Renderer) error {
+	var nodes []ast.Node
+	ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering && (node.Kind() == KindShortcode || node.Kind() == KindShortcodeBlock) {
+			nodes = append(nodes, node)
+		}
+
+		return ast.WalkContinue, nil
+	})
+
+	for i := len(nodes) - 1; i >= 0; i-- {
+		var (
+			tag    *shortcodeTag
+			output *[]byte
+			inner  bytes.Buffer
+		)
+
+		switch node := nodes[i].(type) {
+		case *ShortcodeInline:
+			tag, output = &node.shortcodeTag, &node.output
+		case *ShortcodeBlock:
+			tag, output = &node.shortcodeTag, &node.output
+			if node.HasChildren() {
+				content := ast.NewDocument()
+				for child := node.FirstChild(); child != nil; child = node.FirstChild() {
+					content.AppendChild(content, child)
+				}
+
+				if err := render.Render(&inner, source, content); err != nil {
+					return err
+				}
+			}
+		}
+
+		if templates.Lookup(tag.name) == nil {
+			return fmt.Errorf("shortcode %q: no template defined", tag.name)
+		}
+
+		shortcode := &Shortcode{
+			File:   file,
+			Name:   tag.name,
+			Args:   tag.args,
+			Params: tag.params,
+			Inner:  template.HTML(inner.String()),
+		}
+
+		var buff bytes.Buffer
+		if err := templates.ExecuteTemplate(&buff, tag.name, shortcode); err != nil {
+			return fmt.Errorf("shortcode %q: %w", tag.name, err)
+		}
+
+		*output = buff.Bytes()
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package markdown
+
+import (
+	"bytes"
+	"html"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+// Heading is an entry of a table of contents, containing nested entries for
+// the headings of lower levels which follow it.
+type Heading struct {
+	Level    int
+	Text     string
+	ID       string
+	Children []*Heading
+}
+
+var tocPlaceholder = []byte("<p>[TOC]</p>")
+
+func buildToc(doc ast.Node, source []byte, minLevel, maxLevel int) []*Heading {
+	var (
+		headings []*Heading
+		stack    []*Heading
+	)
+
+	ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		headingNode, ok := node.(*ast.Heading)
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+
+		if headingNode.Level < minLevel || headingNode.Level > maxLevel {
+			return ast.WalkSkipChildren, nil
+		}
+
+		heading := &Heading{
+			Level: headingNode.Level,
+			Text:  string(headingNode.Text(source)),
+		}
+
+		if id, ok := headingNode.AttributeString("id"); ok {
+			if idBytes, ok := id.([]byte); ok {
+				heading.ID = string(idBytes)
+			}
+		}
+
+		for len(stack) > 0 && stack[len(stack)-1].Level >= heading.Level {
+			stack = stack[:len(stack)-1]
+		}
+
+		if len(stack) == 0 {
+			headings = append(headings, heading)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, heading)
+		}
+
+		stack = append(stack, heading)
+		return ast.WalkSkipChildren, nil
+	})
+
+	return headings
+}
+
+func renderToc(headings []*Heading) []byte {
+	var buff bytes.Buffer
+	buff.WriteString("<nav class=\"toc\">\n")
+	renderTocList(&buff, headings)
+	buff.WriteString("</nav>\n")
+	return buff.Bytes()
+}
+
+func renderTocList(buff *bytes.Buffer, headings []*Heading) {
+	if len(headings) == 0 {
+		return
+	}
+
+	buff.WriteString("<ul>\n")
+	for _, heading := range headings {
+		buff.WriteString("<li>")
+		if len(heading.ID) > 0 {
+			buff.WriteString("<a href=\"#" + html.EscapeString(heading.ID) + "\">" + html.EscapeString(heading.Text) + "</a>")
+		} else {
+			buff.WriteString(html.EscapeString(heading.Text))
+		}
+
+		if len(heading.Children) > 0 {
+			buff.WriteString("\n")
+			renderTocList(buff, heading.Children)
+		}
+
+		buff.WriteString("</li>\n")
+	}
+
+	buff.WriteString("</ul>\n")
+}
@@ -0,0 +1,287 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"foosoft.net/projects/goldsmith"
+	"foosoft.net/projects/goldsmith-components/internal/slug"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// KindWikiLink is the node kind of wiki links.
+var KindWikiLink = ast.NewNodeKind("WikiLink")
+
+// WikiLink is an unresolved "[[target|label]]" link; its children contain
+// the link text, which is rendered as-is if the link is not resolved.
+type WikiLink struct {
+	ast.BaseInline
+	Target string
+	Anchor string
+	Label  string
+
+	source string
+}
+
+func (*WikiLink) Kind() ast.NodeKind {
+	return KindWikiLink
+}
+
+func (self *WikiLink) Dump(source []byte, level int) {
+	ast.DumpHelper(self, source, level, map[string]string{"Target": self.Target, "Anchor": self.Anchor}, nil)
+}
+
+func extendWikiLinks(md goldmark.Markdown) {
+	md.Parser().AddOptions(parser.WithInlineParsers(util.Prioritized(new(wikiLinkParser), 199)))
+	md.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(new(wikiLinkRenderer), 500)))
+}
+
+type wikiLinkParser struct{}
+
+func (*wikiLinkParser) Trigger() []byte {
+	return []byte{'['}
+}
+
+func (*wikiLinkParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	if !bytes.HasPrefix(line, []byte("[[")) {
+		return nil
+	}
+
+	end := bytes.Index(line, []byte("]]"))
+	if end < 0 {
+		return nil
+	}
+
+	content := string(line[2:end])
+	if strings.ContainsAny(content, "[]\n") || len(strings.TrimSpace(content)) == 0 {
+		return nil
+	}
+
+	block.Advance(end + 2)
+
+	target, label := content, content
+	if index := strings.Index(content, "|"); index >= 0 {
+		target, label = content[:index], content[index+1:]
+	}
+
+	link := &WikiLink{Label: strings.TrimSpace(label), source: string(line[:end+2])}
+	link.Target = strings.TrimSpace(target)
+	if index := strings.Index(link.Target, "#"); index >= 0 {
+		link.Target, link.Anchor = strings.TrimSpace(link.Target[:index]), link.Target[index+1:]
+	}
+
+	link.AppendChild(link, ast.NewString([]byte(link.Label)))
+	return link
+}
+
+type wikiLinkRenderer struct{}
+
+func (self *wikiLinkRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindWikiLink, self.renderWikiLink)
+}
+
+// renderWikiLink renders wiki links left unresolved because wiki links were
+// disabled after being enabled, which cannot remove their parser, as-is.
+func (*wikiLinkRenderer) renderWikiLink(writer util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		writer.Write(util.EscapeHTML([]byte(node.(*WikiLink).source)))
+	}
+
+	return ast.WalkSkipChildren, nil
+}
+
+// BrokenLink describes a wiki link which could not be resolved.
+type BrokenLink struct {
+	Path   string
+	Target string
+	Reason string
+}
+
+func (self BrokenLink) Error() string {
+	return fmt.Sprintf("%s: wiki link [[%s]]: %s", self.Path, self.Target, self.Reason)
+}
+
+// BrokenLinks is the list of wiki links which could not be resolved during a build.
+type BrokenLinks []BrokenLink
+
+func (self BrokenLinks) Error() string {
+	var messages []string
+	for _, link := range self {
+		messages = append(messages, link.Error())
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+type wikiIndex struct {
+	byPath  map[string][]*goldsmith.File
+	byTitle map[string][]*goldsmith.File
+	bySlug  map[string][]*goldsmith.File
+}
+
+func newWikiIndex(files []*goldsmith.File) *wikiIndex {
+	self := &wikiIndex{
+		byPath:  make(map[string][]*goldsmith.File),
+		byTitle: make(map[string][]*goldsmith.File),
+		bySlug:  make(map[string][]*goldsmith.File),
+	}
+
+	for _, file := range files {
+		addWikiKey(self.byPath, wikiPathKey(file.Path()), file)
+		addWikiKey(self.bySlug, slug.Make(strings.TrimSuffix(file.Name(), file.Ext())), file)
+
+		if title, ok := file.Prop("Title"); ok {
+			if title, ok := title.(string); ok {
+				addWikiKey(self.byTitle, wikiTitleKey(title), file)
+			}
+		}
+
+		if value, ok := file.Prop("Slug"); ok {
+			if value, ok := value.(string); ok {
+				addWikiKey(self.bySlug, slug.Make(value), file)
+			}
+		}
+	}
+
+	return self
+}
+
+func addWikiKey(index map[string][]*goldsmith.File, key string, file *goldsmith.File) {
+	if len(key) == 0 {
+		return
+	}
+
+	for _, indexed := range index[key] {
+		if indexed == file {
+			return
+		}
+	}
+
+	index[key] = append(index[key], file)
+}
+
+// resolve finds the page a link target refers to, trying paths relative to the
+// linking page, paths relative to the source root, titles and slugs in turn.
+func (self *wikiIndex) resolve(file *goldsmith.File, target string) (*goldsmith.File, error) {
+	candidates := [][]*goldsmith.File{
+		self.byPath[wikiPathKey(path.Join(path.Dir(file.Path()), target))],
+		self.byPath[wikiPathKey(target)],
+		self.byTitle[wikiTitleKey(target)],
+		self.bySlug[slug.Make(target)],
+	}
+
+	for _, files := range candidates {
+		switch len(files) {
+		case 0:
+			continue
+		case 1:
+			return files[0], nil
+		default:
+			var paths []string
+			for _, file := range files {
+				paths = append(paths, file.Path())
+			}
+
+			sort.Strings(paths)
+			return nil, fmt.Errorf("ambiguous, matches %s", strings.Join(paths, ", "))
+		}
+	}
+
+	return nil, fmt.Errorf("no matching page")
+}
+
+// resolveWikiLinks replaces wiki links with regular links to the output paths
+// of the pages they refer to, returning the links which could not be resolved.
+func resolveWikiLinks(doc ast.Node, file *goldsmith.File, index *wikiIndex) BrokenLinks {
+	var links []*WikiLink
+	ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if link, ok := node.(*WikiLink); ok && entering {
+			links = append(links, link)
+		}
+
+		return ast.WalkContinue, nil
+	})
+
+	var broken BrokenLinks
+	for _, wikiLink := range links {
+		destination, err := index.destination(file, wikiLink)
+		if err != nil {
+			// Unresolved links are replaced with their text.
+			broken = append(broken, BrokenLink{file.Path(), wikiLink.Target, err.Error()})
+			parent := wikiLink.Parent()
+			for child := wikiLink.FirstChild(); child != nil; child = wikiLink.FirstChild() {
+				parent.InsertBefore(parent, wikiLink, child)
+			}
+
+			parent.RemoveChild(parent, wikiLink)
+			continue
+		}
+
+		link := ast.NewLink()
+		link.Destination = []byte(destination)
+		for child := wikiLink.FirstChild(); child != nil; child = wikiLink.FirstChild() {
+			link.AppendChild(link, child)
+		}
+
+		wikiLink.Parent().ReplaceChild(wikiLink.Parent(), wikiLink, link)
+	}
+
+	return broken
+}
+
+func (self *wikiIndex) destination(file *goldsmith.File, wikiLink *WikiLink) (string, error) {
+	var destination string
+	if len(wikiLink.Target) > 0 {
+		targetFile, err := self.resolve(file, wikiLink.Target)
+		if err != nil {
+			return "", err
+		}
+
+		if destination, err = relativeUrl(file.Path(), htmlPath(targetFile.Path())); err != nil {
+			return "", err
+		}
+	}
+
+	if len(wikiLink.Anchor) > 0 {
+		destination += "#" + wikiLink.Anchor
+	}
+
+	return destination, nil
+}
+
+func htmlPath(filePath string) string {
+	return strings.TrimSuffix(filePath, path.Ext(filePath)) + ".html"
+}
+
+func relativeUrl(fromPath, toPath string) (string, error) {
+	url, err := filepath.Rel(filepath.FromSlash(path.Dir(fromPath)), filepath.FromSlash(toPath))
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.ToSlash(url), nil
+}
+
+func wikiPathKey(filePath string) string {
+	filePath = strings.TrimPrefix(path.Clean("/"+filePath), "/")
+	switch strings.ToLower(path.Ext(filePath)) {
+	case ".md", ".markdown", ".html":
+		filePath = strings.TrimSuffix(filePath, path.Ext(filePath))
+	}
+
+	return strings.ToLower(filePath)
+}
+
+func wikiTitleKey(title string) string {
+	return strings.ToLower(strings.Join(strings.Fields(title), " "))
+}
@@ -0,0 +1,376 @@
+package rst
+
+import (
+	"encoding/csv"
+	"strconv"
+	"strings"
+)
+
+// admonitions maps the names of admonition directives to their titles.
+var admonitions = map[string]string{
+	"attention": "Attention",
+	"caution":   "Caution",
+	"danger":    "Danger",
+	"error":     "Error",
+	"hint":      "Hint",
+	"important": "Important",
+	"note":      "Note",
+	"seealso":   "See also",
+	"tip":       "Tip",
+	"warning":   "Warning",
+}
+
+// ignoredDirectives are Sphinx directives which only affect the structure of a
+// project or its index, and which produce no output within a document.
+var ignoredDirectives = map[string]bool{
+	"index":         true,
+	"meta":          true,
+	"toctree":       true,
+	"sectionauthor": true,
+}
+
+type directive struct {
+	name    string
+	line    int
+	args    string
+	options map[string]string
+	content []line
+}
+
+// parseExplicit parses explicit markup starting with "..": directives,
+// hyperlink targets, footnotes, substitution definitions and comments.
+func (self *parser) parseExplicit(lines []line, i int) ([]*node, int, error) {
+	current := lines[i]
+	body, end := indented(lines, i+1, 1)
+
+	if matches := footnoteExp.FindStringSubmatch(current.text); matches != nil {
+		return self.parseFootnote(current, matches[1], matches[2], body, end)
+	}
+
+	if matches := targetExp.FindStringSubmatch(current.text); matches != nil {
+		name := strings.Trim(matches[1], "`")
+		url := matches[2]
+		for _, l := range body {
+			url += strings.TrimSpace(l.text)
+		}
+
+		url = strings.ReplaceAll(url, " ", "")
+		if name == "_" {
+			self.anonymous = append(self.anonymous, url)
+			return nil, end, nil
+		}
+
+		if len(url) > 0 {
+			self.targets[normalizeName(name)] = target{url, current.number}
+			return nil, end, nil
+		}
+
+		// Internal targets point to the element which follows them.
+		id := self.uniqueId(name)
+		self.targets[normalizeName(name)] = target{"#" + id, current.number}
+		return []*node{{kind: kindTarget, line: current.number, id: id}}, end, nil
+	}
+
+	if matches := substExp.FindStringSubmatch(current.text); matches != nil {
+		return nil, end, self.parseSubstitution(current, matches[1], matches[2], matches[3], body)
+	}
+
+	if matches := directiveExp.FindStringSubmatch(current.text); matches != nil {
+		nodes, err := self.parseDirective(current, matches[1], matches[2], body)
+		return nodes, end, err
+	}
+
+	// Anything else is a comment.
+	return nil, end, nil
+}
+
+func (self *parser) parseFootnote(current line, label, text string, body []line, end int) ([]*node, int, error) {
+	content := append([]line{{text, current.number}}, body...)
+	children, err := self.parseBlocks(dedent(content))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	footnote := &node{kind: kindFootnote, line: current.number, children: children}
+	switch {
+	case label == "#" || strings.HasPrefix(label, "#"):
+		footnote.text = self.nextFootnoteNumber()
+		footnote.id = "footnote-" + footnote.text
+		self.autoFootnotes = append(self.autoFootnotes, footnoteOf(footnote))
+		if len(label) > 1 {
+			self.footnotes[normalizeName(label)] = footnoteOf(footnote)
+		}
+	case isNumber(label):
+		footnote.text = label
+		footnote.id = "footnote-" + label
+		self.footnotes[label] = footnoteOf(footnote)
+	case label == "*" || len(label) == 0:
+		return nil, 0, &Error{current.number, "symbol footnotes are not supported"}
+	default:
+		footnote.text = label
+		footnote.id = "citation-" + slugify(label)
+		footnote.class = "citation"
+		self.footnotes[normalizeName(label)] = footnoteOf(footnote)
+	}
+
+	return []*node{footnote}, end, nil
+}
+
+func footnoteOf(node *node) footnote {
+	return footnote{id: node.id, label: node.text}
+}
+
+func (self *parser) nextFootnoteNumber() string {
+	for {
+		self.footnoteCount++
+		number := strconv.Itoa(self.footnoteCount)
+		if _, ok := self.footnotes[number]; !ok {
+			return number
+		}
+	}
+}
+
+func isNumber(text string) bool {
+	_, err := strconv.Atoi(text)
+	return err == nil
+}
+
+func (self *parser) parseSubstitution(current line, name, kind, args string, body []line) error {
+	for _, l := range body {
+		args += " " + strings.TrimSpace(l.text)
+	}
+
+	args = strings.TrimSpace(args)
+	switch kind {
+	case "replace":
+		self.substitutions[name] = args
+	case "image":
+		self.substitutions[name] = "\x00" + args
+	default:
+		return &Error{current.number, "unsupported substitution directive " + strconv.Quote(kind)}
+	}
+
+	return nil
+}
+
+// parseDirectiveBody splits the body of a directive into arguments, which may
+// continue on the lines following the directive, options and content.
+func parseDirectiveBody(name string, number int, args string, body []line, arguments bool) *directive {
+	result := &directive{name: name, line: number, args: args, options: make(map[string]string)}
+
+	index := 0
+	for ; arguments && index < len(body) && !isBlank(body[index]) && !optionExp.MatchString(body[index].text); index++ {
+		result.args += " " + strings.TrimSpace(body[index].text)
+	}
+
+	for ; index < len(body) && optionExp.MatchString(body[index].text); index++ {
+		matches := optionExp.FindStringSubmatch(body[index].text)
+		result.options[matches[1]] = strings.TrimSpace(matches[2])
+	}
+
+	result.args = strings.TrimSpace(result.args)
+	result.content = dedent(body[index:])
+	for len(result.content) > 0 && isBlank(result.content[0]) {
+		result.content = result.content[1:]
+	}
+
+	return result
+}
+
+func (self *parser) parseDirective(current line, name, args string, body []line) ([]*node, error) {
+	name = strings.ToLower(name)
+	if title, ok := admonitions[name]; ok {
+		// Admonitions have no arguments, so text on the first line is content.
+		if len(args) > 0 {
+			content := append([]line{{args, current.number}}, body...)
+			return self.admonition(&directive{name: name, line: current.number, content: dedent(content)}, name, title)
+		}
+
+		return self.admonition(parseDirectiveBody(name, current.number, "", body, false), name, title)
+	}
+
+	d := parseDirectiveBody(name, current.number, args, body, true)
+	switch name {
+	case "admonition":
+		if len(d.args) == 0 {
+			return nil, &Error{d.line, "admonition requires a title"}
+		}
+
+		return self.admonition(d, "admonition", d.args)
+	case "topic", "sidebar":
+		children, err := self.parseBlocks(d.content)
+		if err != nil {
+			return nil, err
+		}
+
+		return []*node{{kind: kindTopic, line: d.line, class: name, text: d.args, children: children}}, nil
+	case "rubric":
+		return []*node{{kind: kindRubric, line: d.line, text: d.args}}, nil
+	case "code", "code-block", "sourcecode":
+		language := d.args
+		if len(language) == 0 {
+			language = self.language
+		}
+
+		return []*node{{kind: kindCode, line: d.line, class: language, text: joinLines(d.content)}}, nil
+	case "highlight":
+		self.language = d.args
+		return nil, nil
+	case "math":
+		text := d.args
+		if len(d.content) > 0 {
+			text = joinLines(d.content)
+		}
+
+		return []*node{{kind: kindMath, line: d.line, text: text}}, nil
+	case "raw":
+		if strings.ToLower(d.args) != "html" {
+			return nil, nil
+		}
+
+		return []*node{{kind: kindRaw, line: d.line, text: joinLines(d.content)}}, nil
+	case "image":
+		if len(d.args) == 0 {
+			return nil, &Error{d.line, "image requires a URI"}
+		}
+
+		return []*node{imageNode(d)}, nil
+	case "figure":
+		if len(d.args) == 0 {
+			return nil, &Error{d.line, "figure requires a URI"}
+		}
+
+		children, err := self.parseBlocks(d.content)
+		if err != nil {
+			return nil, err
+		}
+
+		return []*node{{kind: kindFigure, line: d.line, children: append([]*node{imageNode(d)}, children...)}}, nil
+	case "contents":
+		title := d.args
+		if len(title) == 0 {
+			title = "Contents"
+		}
+
+		depth := 6
+		if value, err := strconv.Atoi(d.options["depth"]); err == nil {
+			depth = value
+		}
+
+		return []*node{{kind: kindContents, line: d.line, text: title, level: depth}}, nil
+	case "list-table":
+		return self.parseListTable(d)
+	case "csv-table":
+		return self.parseCsvTable(d)
+	}
+
+	if ignoredDirectives[name] {
+		return nil, nil
+	}
+
+	return nil, &Error{current.number, "unsupported directive " + strconv.Quote(name)}
+}
+
+func (self *parser) admonition(d *directive, class, title string) ([]*node, error) {
+	children, err := self.parseBlocks(d.content)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*node{{kind: kindAdmonition, line: d.line, class: class, text: title, children: children}}, nil
+}
+
+func imageNode(d *directive) *node {
+	attrs := map[string]string{"src": strings.ReplaceAll(d.args, " ", "")}
+	for _, key := range []string{"alt", "width", "height", "class", "target", "align"} {
+		if value, ok := d.options[key]; ok {
+			attrs[key] = value
+		}
+	}
+
+	return &node{kind: kindImage, line: d.line, attrs: attrs}
+}
+
+func headerRows(d *directive) (int, error) {
+	value, ok := d.options["header-rows"]
+	if !ok {
+		return 0, nil
+	}
+
+	rows, err := strconv.Atoi(value)
+	if err != nil || rows < 0 {
+		return 0, &Error{d.line, "invalid header-rows option " + strconv.Quote(value)}
+	}
+
+	return rows, nil
+}
+
+// parseListTable parses a table given as a bullet list of rows, each of which
+// is a bullet list of cells.
+func (self *parser) parseListTable(d *directive) ([]*node, error) {
+	children, err := self.parseBlocks(d.content)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(children) != 1 || children[0].kind != kindBulletList {
+		return nil, &Error{d.line, "list-table content must be a bullet list"}
+	}
+
+	table := &node{kind: kindTable, line: d.line}
+	for _, item := range children[0].children {
+		if len(item.children) != 1 || item.children[0].kind != kindBulletList {
+			return nil, &Error{item.line, "list-table rows must be bullet lists"}
+		}
+
+		table.rows = append(table.rows, item.children[0].children)
+	}
+
+	if table.header, err = headerRows(d); err != nil {
+		return nil, err
+	}
+
+	return []*node{table}, nil
+}
+
+func (self *parser) parseCsvTable(d *directive) ([]*node, error) {
+	var records [][]string
+	if header, ok := d.options["header"]; ok {
+		reader := csv.NewReader(strings.NewReader(header))
+		reader.TrimLeadingSpace = true
+		record, err := reader.Read()
+		if err != nil {
+			return nil, &Error{d.line, "invalid csv-table header: " + err.Error()}
+		}
+
+		records = append(records, record)
+	}
+
+	reader := csv.NewReader(strings.NewReader(joinLines(d.content)))
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+	content, err := reader.ReadAll()
+	if err != nil {
+		return nil, &Error{d.line, "invalid csv-table content: " + err.Error()}
+	}
+
+	table := &node{kind: kindTable, line: d.line}
+	if table.header, err = headerRows(d); err != nil {
+		return nil, err
+	}
+
+	if len(records) > 0 {
+		table.header += len(records)
+	}
+
+	for _, record := range append(records, content...) {
+		var row []*node
+		for _, field := range record {
+			row = append(row, &node{kind: kindListItem, children: []*node{{kind: kindParagraph, line: d.line, text: field}}})
+		}
+
+		table.rows = append(table.rows, row)
+	}
+
+	return []*node{table}, nil
+}
@@ -0,0 +1,467 @@
+package rst
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	roleExp       = regexp.MustCompile("^:([\\w.+:-]+?):`")
+	suffixRoleExp = regexp.MustCompile(`^:([\w.+:-]+?):`)
+	abbrExp       = regexp.MustCompile(`(?s)^(.*?)\s*\((.*)\)$`)
+	referenceExp  = regexp.MustCompile(`^([A-Za-z0-9](?:[\w.+-]*[A-Za-z0-9])?)(__?)`)
+	uriExp        = regexp.MustCompile(`^(?:https?|ftp)://[^\s<>]+|^mailto:[^\s<>]+`)
+	embeddedExp   = regexp.MustCompile(`(?s)^(.*?)\s*<([^<>]+)>$`)
+	footRefExp    = regexp.MustCompile(`^\[(#?[\w-]*)\]_`)
+)
+
+// isStartBoundary checks if inline markup may start after the character.
+func isStartBoundary(c rune) bool {
+	return c == 0 || unicode.IsSpace(c) || strings.ContainsRune("-:/'\"<([{", c) || unicode.In(c, unicode.Ps, unicode.Pi, unicode.Pd)
+}
+
+// isEndBoundary checks if inline markup may end before the character.
+func isEndBoundary(c rune) bool {
+	return c == 0 || unicode.IsSpace(c) || strings.ContainsRune("-.,:;!?\\/'\")]}>", c) || unicode.In(c, unicode.Pe, unicode.Pf, unicode.Pd, unicode.Po)
+}
+
+func runeBefore(text string, index int) rune {
+	if index == 0 {
+		return 0
+	}
+
+	c, _ := utf8.DecodeLastRuneInString(text[:index])
+	return c
+}
+
+func runeAt(text string, index int) rune {
+	if index >= len(text) {
+		return 0
+	}
+
+	c, _ := utf8.DecodeRuneInString(text[index:])
+	return c
+}
+
+// findEnd finds the end-string of inline markup within text, starting at the
+// given offset, returning its index or -1.
+func findEnd(text string, start int, end string) int {
+	for index := start; index < len(text); index++ {
+		if text[index] == '\\' && end != "``" {
+			index++
+			continue
+		}
+
+		if strings.HasPrefix(text[index:], end) && index > start && !unicode.IsSpace(runeBefore(text, index)) && isEndBoundary(runeAt(text, index+len(end))) {
+			return index
+		}
+	}
+
+	return -1
+}
+
+// findInterpretedEnd finds the closing backquote of interpreted text or a
+// hyperlink reference, which may be followed by "_", "__" or a role.
+func findInterpretedEnd(text string) int {
+	for index := 1; index < len(text); index++ {
+		if text[index] == '\\' {
+			index++
+			continue
+		}
+
+		if text[index] != '`' || index == 1 || unicode.IsSpace(runeBefore(text, index)) {
+			continue
+		}
+
+		suffix := text[index+1:]
+		switch {
+		case strings.HasPrefix(suffix, "__"):
+			suffix = suffix[2:]
+		case strings.HasPrefix(suffix, "_"):
+			suffix = suffix[1:]
+		default:
+			if matches := suffixRoleExp.FindString(suffix); len(matches) > 0 {
+				suffix = suffix[len(matches):]
+			}
+		}
+
+		if isEndBoundary(runeAt(suffix, 0)) {
+			return index
+		}
+	}
+
+	return -1
+}
+
+// unescape removes backslash escapes, dropping escaped whitespace.
+func unescape(text string) string {
+	var builder strings.Builder
+	for index := 0; index < len(text); index++ {
+		if text[index] == '\\' && index+1 < len(text) {
+			index++
+			if text[index] == ' ' || text[index] == '\n' {
+				continue
+			}
+		}
+
+		builder.WriteByte(text[index])
+	}
+
+	return builder.String()
+}
+
+func escape(text string) string {
+	return html.EscapeString(unescape(text))
+}
+
+// inline renders inline markup to HTML.
+func (self *renderer) inline(text string, number int) (string, error) {
+	var builder strings.Builder
+	plain := 0
+
+	flush := func(end int) {
+		builder.WriteString(escape(text[plain:end]))
+	}
+
+	for index := 0; index < len(text); {
+		c := text[index]
+		if c == '\\' {
+			index += 2
+			continue
+		}
+
+		if !isStartBoundary(runeBefore(text, index)) {
+			index++
+			continue
+		}
+
+		markup, length, err := self.inlineMarkup(text[index:], number)
+		if err != nil {
+			return "", err
+		}
+
+		if length == 0 {
+			index++
+			continue
+		}
+
+		flush(index)
+		builder.WriteString(markup)
+		index += length
+		plain = index
+	}
+
+	flush(len(text))
+	return builder.String(), nil
+}
+
+// inlineMarkup renders the markup at the start of text, returning its length,
+// or zero if no markup starts there.
+func (self *renderer) inlineMarkup(text string, number int) (string, int, error) {
+	switch {
+	case strings.HasPrefix(text, "``"):
+		if end := findEnd(text, 2, "``"); end >= 0 && !unicode.IsSpace(runeAt(text, 2)) {
+			return "<code>" + html.EscapeString(text[2:end]) + "</code>", end + 2, nil
+		}
+	case strings.HasPrefix(text, "**"):
+		if end := findEnd(text, 2, "**"); end >= 0 && !unicode.IsSpace(runeAt(text, 2)) {
+			return "<strong>" + escape(text[2:end]) + "</strong>", end + 2, nil
+		}
+	case strings.HasPrefix(text, "*"):
+		if end := findEnd(text, 1, "*"); end >= 0 && !unicode.IsSpace(runeAt(text, 1)) {
+			return "<em>" + escape(text[1:end]) + "</em>", end + 1, nil
+		}
+	case strings.HasPrefix(text, "`"):
+		return self.interpreted(text, "", 0, number)
+	case strings.HasPrefix(text, ":"):
+		if matches := roleExp.FindStringSubmatch(text); matches != nil {
+			return self.interpreted(text[len(matches[0])-1:], matches[1], len(matches[0])-1, number)
+		}
+	case strings.HasPrefix(text, "|"):
+		if end := findEnd(text, 1, "|"); end >= 0 && !unicode.IsSpace(runeAt(text, 1)) {
+			return self.substitution(text, end, number)
+		}
+	case strings.HasPrefix(text, "["):
+		if matches := footRefExp.FindStringSubmatch(text); matches != nil && isEndBoundary(runeAt(text, len(matches[0]))) {
+			markup, err := self.footnoteReference(matches[1], number)
+			return markup, len(matches[0]), err
+		}
+	}
+
+	if matches := uriExp.FindString(text); len(matches) > 0 {
+		uri := strings.TrimRight(matches, ".,;:!?)'\"")
+		return "<a href=\"" + html.EscapeString(uri) + "\">" + html.EscapeString(uri) + "</a>", len(uri), nil
+	}
+
+	if matches := referenceExp.FindStringSubmatch(text); matches != nil && isEndBoundary(runeAt(text, len(matches[0]))) {
+		markup, err := self.reference(matches[1], matches[1], matches[2] == "__", number)
+		return markup, len(matches[0]), err
+	}
+
+	return "", 0, nil
+}
+
+// interpreted renders interpreted text and hyperlink references enclosed in
+// backquotes, with an optional role given before or after the text.
+func (self *renderer) interpreted(text, role string, offset, number int) (string, int, error) {
+	end := findInterpretedEnd(text)
+	if end < 0 || unicode.IsSpace(runeAt(text, 1)) {
+		return "", 0, nil
+	}
+
+	content := text[1:end]
+	length := end + 1
+
+	switch suffix := text[length:]; {
+	case len(role) == 0 && strings.HasPrefix(suffix, "__") && isEndBoundary(runeAt(suffix, 2)):
+		markup, err := self.hyperlink(content, true, number)
+		return markup, offset + length + 2, err
+	case len(role) == 0 && strings.HasPrefix(suffix, "_") && isEndBoundary(runeAt(suffix, 1)):
+		markup, err := self.hyperlink(content, false, number)
+		return markup, offset + length + 1, err
+	}
+
+	if len(role) == 0 {
+		if matches := suffixRoleExp.FindStringSubmatch(text[length:]); matches != nil {
+			role = matches[1]
+			length += len(matches[0])
+		}
+	}
+
+	markup, err := self.role(role, content, number)
+	return markup, offset + length, err
+}
+
+func (self *renderer) role(role, content string, number int) (string, error) {
+	switch role {
+	case "", "title-reference", "title", "t":
+		return "<cite>" + escape(content) + "</cite>", nil
+	case "emphasis":
+		return "<em>" + escape(content) + "</em>", nil
+	case "strong":
+		return "<strong>" + escape(content) + "</strong>", nil
+	case "literal", "code", "samp", "file", "command":
+		return "<code>" + escape(content) + "</code>", nil
+	case "kbd":
+		return "<kbd>" + escape(content) + "</kbd>", nil
+	case "sub", "subscript":
+		return "<sub>" + escape(content) + "</sub>", nil
+	case "sup", "superscript":
+		return "<sup>" + escape(content) + "</sup>", nil
+	case "math":
+		return "<span class=\"math\">" + html.EscapeString(content) + "</span>", nil
+	case "abbr":
+		if matches := abbrExp.FindStringSubmatch(content); matches != nil {
+			return "<abbr title=\"" + escape(matches[2]) + "\">" + escape(matches[1]) + "</abbr>", nil
+		}
+
+		return "<abbr>" + escape(content) + "</abbr>", nil
+	case "doc":
+		title, target := splitEmbedded(content)
+		return "<a href=\"" + html.EscapeString(target+".html") + "\">" + escape(title) + "</a>", nil
+	case "ref":
+		title, target := splitEmbedded(content)
+		if destination, ok := self.parser.targets[normalizeName(target)]; ok {
+			return "<a href=\"" + html.EscapeString(destination.url) + "\">" + escape(title) + "</a>", nil
+		}
+
+		return "", &Error{number, fmt.Sprintf("unknown target %q", target)}
+	}
+
+	// Roles defined by Sphinx domains, such as :py:func:, are rendered as code.
+	class := strings.NewReplacer(":", "-", ".", "-").Replace(role)
+	return "<code class=\"" + html.EscapeString(class) + "\">" + escape(content) + "</code>", nil
+}
+
+func splitEmbedded(content string) (string, string) {
+	if matches := embeddedExp.FindStringSubmatch(content); matches != nil && len(matches[1]) > 0 {
+		return matches[1], matches[2]
+	}
+
+	return content, content
+}
+
+// hyperlink renders a reference in backquotes, which either embeds its URI as
+// in `text <uri>`_ or refers to a named target.
+func (self *renderer) hyperlink(content string, anonymous bool, number int) (string, error) {
+	if matches := embeddedExp.FindStringSubmatch(content); matches != nil {
+		title, uri := matches[1], strings.Join(strings.Fields(matches[2]), "")
+		if len(title) == 0 {
+			title = uri
+		}
+
+		// An embedded URI ending in "_" is a reference to a named target.
+		if strings.HasSuffix(uri, "_") && !strings.HasSuffix(uri, "\\_") {
+			return self.reference(title, strings.TrimSuffix(uri, "_"), false, number)
+		}
+
+		return "<a href=\"" + html.EscapeString(rewriteLink(unescape(uri))) + "\">" + escape(title) + "</a>", nil
+	}
+
+	return self.reference(content, content, anonymous, number)
+}
+
+func (self *renderer) reference(title, name string, anonymous bool, number int) (string, error) {
+	var destination string
+	if anonymous {
+		if self.anonymous >= len(self.parser.anonymous) {
+			return "", &Error{number, "anonymous reference without matching target"}
+		}
+
+		destination = self.parser.anonymous[self.anonymous]
+		self.anonymous++
+	} else {
+		target, ok := self.parser.targets[normalizeName(unescape(name))]
+		if !ok {
+			return "", &Error{number, fmt.Sprintf("unknown target %q", unescape(name))}
+		}
+
+		destination = target.url
+	}
+
+	// Targets may be aliases of other targets, such as ".. _alias: name_".
+	for hops := 0; strings.HasSuffix(destination, "_") && hops < 10; hops++ {
+		target, ok := self.parser.targets[normalizeName(strings.Trim(strings.TrimSuffix(destination, "_"), "`"))]
+		if !ok {
+			break
+		}
+
+		destination = target.url
+	}
+
+	return "<a href=\"" + html.EscapeString(rewriteLink(destination)) + "\">" + escape(title) + "</a>", nil
+}
+
+func (self *renderer) substitution(text string, end, number int) (string, int, error) {
+	name := text[1:end]
+	length := end + 1
+	link := ""
+	if strings.HasPrefix(text[length:], "_") && isEndBoundary(runeAt(text, length+1)) {
+		link = name
+		length++
+	}
+
+	value, ok := self.parser.substitutions[name]
+	if !ok {
+		return "", 0, &Error{number, fmt.Sprintf("undefined substitution %q", name)}
+	}
+
+	var markup string
+	if strings.HasPrefix(value, "\x00") {
+		uri := strings.TrimPrefix(value, "\x00")
+		markup = "<img src=\"" + html.EscapeString(uri) + "\" alt=\"" + html.EscapeString(name) + "\">"
+	} else {
+		var err error
+		if markup, err = self.inline(value, number); err != nil {
+			return "", 0, err
+		}
+	}
+
+	if len(link) > 0 {
+		target, ok := self.parser.targets[normalizeName(link)]
+		if !ok {
+			return "", 0, &Error{number, fmt.Sprintf("unknown target %q", link)}
+		}
+
+		markup = "<a href=\"" + html.EscapeString(rewriteLink(target.url)) + "\">" + markup + "</a>"
+	}
+
+	return markup, length, nil
+}
+
+func (self *renderer) footnoteReference(label string, number int) (string, error) {
+	var (
+		footnote footnote
+		ok       bool
+	)
+
+	if label == "#" {
+		if ok = self.autoFootnotes < len(self.parser.autoFootnotes); ok {
+			footnote = self.parser.autoFootnotes[self.autoFootnotes]
+			self.autoFootnotes++
+		}
+	} else if isNumber(label) {
+		footnote, ok = self.parser.footnotes[label]
+	} else {
+		footnote, ok = self.parser.footnotes[normalizeName(label)]
+	}
+
+	if !ok {
+		return "", &Error{number, fmt.Sprintf("unknown footnote or citation [%s]", label)}
+	}
+
+	class := "footnote-reference"
+	if strings.HasPrefix(footnote.id, "citation-") {
+		class = "citation-reference"
+	}
+
+	return fmt.Sprintf("<a class=%q href=\"#%s\">[%s]</a>", class, html.EscapeString(footnote.id), html.EscapeString(footnote.label)), nil
+}
+
+// rewriteLink points relative links to reStructuredText sources at the HTML
+// files they are rendered to.
+func rewriteLink(destination string) string {
+	if parsed, err := url.Parse(destination); err != nil || len(parsed.Scheme) > 0 || len(parsed.Host) > 0 {
+		return destination
+	}
+
+	linkPath, suffix := destination, ""
+	if index := strings.IndexAny(destination, "?#"); index >= 0 {
+		linkPath, suffix = destination[:index], destination[index:]
+	}
+
+	if strings.ToLower(path.Ext(linkPath)) == ".rst" {
+		return strings.TrimSuffix(linkPath, path.Ext(linkPath)) + ".html" + suffix
+	}
+
+	return destination
+}
+
+// normalizeName normalizes reference names, which are case-insensitive and
+// whitespace-neutral.
+func normalizeName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+func slugify(text string) string {
+	var (
+		slug   strings.Builder
+		hyphen bool
+	)
+
+	for _, c := range unescape(text) {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) {
+			if hyphen && slug.Len() > 0 {
+				slug.WriteRune('-')
+			}
+
+			slug.WriteRune(unicode.ToLower(c))
+			hyphen = false
+		} else {
+			hyphen = true
+		}
+	}
+
+	return slug.String()
+}
+
+func (self *parser) uniqueId(text string) string {
+	id := slugify(text)
+	if len(id) == 0 {
+		id = "section"
+	}
+
+	count := self.ids[id]
+	self.ids[id] = count + 1
+	if count > 0 {
+		return fmt.Sprintf("%s-%d", id, count)
+	}
+
+	return id
+}
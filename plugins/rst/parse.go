@@ -0,0 +1,753 @@
+package rst
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type nodeKind int
+
+const (
+	kindHeading nodeKind = iota
+	kindParagraph
+	kindLiteral
+	kindCode
+	kindBlockQuote
+	kindBulletList
+	kindEnumList
+	kindListItem
+	kindDefinitionList
+	kindDefinition
+	kindFieldList
+	kindField
+	kindTable
+	kindTransition
+	kindLineBlock
+	kindAdmonition
+	kindImage
+	kindFigure
+	kindRaw
+	kindMath
+	kindTarget
+	kindFootnote
+	kindContents
+	kindRubric
+	kindTopic
+)
+
+// node is an element of a parsed document. Fields are used depending on the
+// kind of node; text contains inline markup unless the node is literal.
+type node struct {
+	kind     nodeKind
+	line     int
+	text     string
+	level    int
+	id       string
+	class    string
+	attrs    map[string]string
+	rows     [][]*node
+	header   int
+	children []*node
+}
+
+type line struct {
+	text   string
+	number int
+}
+
+// Error describes invalid or unsupported markup at a line of a document.
+type Error struct {
+	Line    int
+	Message string
+}
+
+func (self *Error) Error() string {
+	return fmt.Sprintf("line %d: %s", self.Line, self.Message)
+}
+
+type target struct {
+	url  string
+	line int
+}
+
+type footnote struct {
+	id    string
+	label string
+}
+
+type parser struct {
+	styles        []string
+	ids           map[string]int
+	targets       map[string]target
+	anonymous     []string
+	substitutions map[string]string
+	footnotes     map[string]footnote
+	autoFootnotes []footnote
+	footnoteCount int
+	headings      []*node
+	language      string
+}
+
+func newParser() *parser {
+	return &parser{
+		ids:           make(map[string]int),
+		targets:       make(map[string]target),
+		substitutions: make(map[string]string),
+		footnotes:     make(map[string]footnote),
+	}
+}
+
+func splitLines(source string) []line {
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+
+	var lines []line
+	for i, text := range strings.Split(source, "\n") {
+		lines = append(lines, line{expandTabs(strings.TrimRightFunc(text, unicode.IsSpace)), i + 1})
+	}
+
+	return lines
+}
+
+func expandTabs(text string) string {
+	if !strings.Contains(text, "\t") {
+		return text
+	}
+
+	var builder strings.Builder
+	column := 0
+	for _, c := range text {
+		if c == '\t' {
+			spaces := 8 - column%8
+			builder.WriteString(strings.Repeat(" ", spaces))
+			column += spaces
+		} else {
+			builder.WriteRune(c)
+			column++
+		}
+	}
+
+	return builder.String()
+}
+
+func indentOf(text string) int {
+	return len(text) - len(strings.TrimLeft(text, " "))
+}
+
+func isBlank(l line) bool {
+	return len(l.text) == 0
+}
+
+// indented returns the lines from start which are blank or indented by at
+// least minIndent, dedented by the smallest indentation among them.
+func indented(lines []line, start, minIndent int) ([]line, int) {
+	end := start
+	for end < len(lines) && (isBlank(lines[end]) || indentOf(lines[end].text) >= minIndent) {
+		end++
+	}
+
+	block := lines[start:end]
+	for len(block) > 0 && isBlank(block[len(block)-1]) {
+		block = block[:len(block)-1]
+	}
+
+	return dedent(block), end
+}
+
+func dedent(lines []line) []line {
+	minIndent := -1
+	for _, l := range lines {
+		if !isBlank(l) {
+			if indent := indentOf(l.text); minIndent < 0 || indent < minIndent {
+				minIndent = indent
+			}
+		}
+	}
+
+	result := make([]line, len(lines))
+	for i, l := range lines {
+		result[i] = l
+		if !isBlank(l) {
+			result[i].text = l.text[minIndent:]
+		}
+	}
+
+	return result
+}
+
+func joinLines(lines []line) string {
+	var texts []string
+	for _, l := range lines {
+		texts = append(texts, l.text)
+	}
+
+	return strings.Join(texts, "\n")
+}
+
+const adornmentChars = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+
+func isAdornment(text string) bool {
+	if len(text) < 2 || !strings.ContainsRune(adornmentChars, rune(text[0])) {
+		return false
+	}
+
+	return strings.Count(text, text[:1]) == len(text)
+}
+
+var (
+	bulletExp     = regexp.MustCompile(`^([-*+•‣⁃])( +|$)`)
+	enumExp       = regexp.MustCompile(`^(\(?)(\d+|#|[a-zA-Z])([.)])( +|$)`)
+	fieldExp      = regexp.MustCompile(`^:((?:[^:\\]|\\.)+):( +|$)`)
+	gridBorderExp = regexp.MustCompile(`^\+(?:[-=]+\+)+$`)
+	simpleExp     = regexp.MustCompile(`^=+(?: +=+)+$`)
+	directiveExp  = regexp.MustCompile(`^\.\. +([\w.+:-]+?)::(?: +(.*))?$`)
+	substExp      = regexp.MustCompile(`^\.\. +\|([^|]+)\| +([\w.+:-]+?)::(?: +(.*))?$`)
+	targetExp     = regexp.MustCompile("^\\.\\. +_(`[^`]+`|[^:]+|_):(?: +(.*))?$")
+	anonTargetExp = regexp.MustCompile(`^__ +(.*)$`)
+	footnoteExp   = regexp.MustCompile(`^\.\. +\[(#?[\w-]*|\*)\](?: +(.*))?$`)
+	optionExp     = regexp.MustCompile(`^:([\w-]+):(?: +(.*))?$`)
+)
+
+// parseBlocks parses a sequence of body elements from lines which have been
+// dedented to the indentation of the enclosing element.
+func (self *parser) parseBlocks(lines []line) ([]*node, error) {
+	var nodes []*node
+	for i := 0; i < len(lines); {
+		if isBlank(lines[i]) {
+			i++
+			continue
+		}
+
+		parsed, next, err := self.parseBlock(lines, i)
+		if err != nil {
+			return nil, err
+		}
+
+		nodes = append(nodes, parsed...)
+		i = next
+	}
+
+	return nodes, nil
+}
+
+func (self *parser) parseBlock(lines []line, i int) ([]*node, int, error) {
+	current := lines[i]
+	text := current.text
+	next := ""
+	if i+1 < len(lines) {
+		next = lines[i+1].text
+	}
+
+	if indentOf(text) > 0 {
+		block, end := indented(lines, i, 1)
+		children, err := self.parseBlocks(block)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		return []*node{{kind: kindBlockQuote, line: current.number, children: children}}, end, nil
+	}
+
+	switch {
+	case isAdornment(text) && i+2 < len(lines) && lines[i+2].text == text && len(next) > 0:
+		return self.parseHeading(current, strings.TrimSpace(next), text[:1]+"/"+text[:1]), i + 3, nil
+	case len(next) > 0 && isAdornment(next) && indentOf(next) == 0 && utf8.RuneCountInString(next) >= utf8.RuneCountInString(text) && !isAdornment(text):
+		return self.parseHeading(current, text, next[:1]), i + 2, nil
+	case isAdornment(text) && len(text) >= 4 && len(next) == 0:
+		return []*node{{kind: kindTransition, line: current.number}}, i + 1, nil
+	case gridBorderExp.MatchString(text):
+		return self.parseGridTable(lines, i)
+	case simpleExp.MatchString(text):
+		return self.parseSimpleTable(lines, i)
+	case strings.HasPrefix(text, ".. ") || text == "..":
+		return self.parseExplicit(lines, i)
+	case anonTargetExp.MatchString(text):
+		self.anonymous = append(self.anonymous, strings.TrimSpace(anonTargetExp.FindStringSubmatch(text)[1]))
+		return nil, i + 1, nil
+	case bulletExp.MatchString(text):
+		return self.parseList(lines, i, kindBulletList)
+	case enumExp.MatchString(text) && (len(next) == 0 || indentOf(next) > 0 || enumExp.MatchString(next)):
+		return self.parseList(lines, i, kindEnumList)
+	case fieldExp.MatchString(text):
+		return self.parseFieldList(lines, i)
+	case strings.HasPrefix(text, "| ") || text == "|":
+		return self.parseLineBlock(lines, i)
+	case strings.HasPrefix(text, ">>> "):
+		end := i
+		for end < len(lines) && !isBlank(lines[end]) {
+			end++
+		}
+
+		return []*node{{kind: kindLiteral, line: current.number, text: joinLines(lines[i:end])}}, end, nil
+	case len(next) > 0 && indentOf(next) > 0:
+		return self.parseDefinitionList(lines, i)
+	}
+
+	return self.parseParagraph(lines, i)
+}
+
+func (self *parser) parseHeading(current line, title, style string) []*node {
+	level := -1
+	for index, existing := range self.styles {
+		if existing == style {
+			level = index
+		}
+	}
+
+	if level < 0 {
+		self.styles = append(self.styles, style)
+		level = len(self.styles) - 1
+	}
+
+	heading := &node{kind: kindHeading, line: current.number, text: title, level: level + 1, id: self.uniqueId(title)}
+	self.headings = append(self.headings, heading)
+
+	// Section titles are implicit targets, unless a target of the same name is defined.
+	if _, ok := self.targets[normalizeName(title)]; !ok {
+		self.targets[normalizeName(title)] = target{"#" + heading.id, current.number}
+	}
+
+	return []*node{heading}
+}
+
+func (self *parser) parseParagraph(lines []line, i int) ([]*node, int, error) {
+	start := i
+	for i < len(lines) && !isBlank(lines[i]) && indentOf(lines[i].text) == 0 {
+		i++
+	}
+
+	text := joinLines(lines[start:i])
+	if !strings.HasSuffix(text, "::") {
+		return []*node{{kind: kindParagraph, line: lines[start].number, text: text}}, i, nil
+	}
+
+	// A paragraph ending with "::" introduces an indented literal block; the
+	// marker is removed, or replaced with a colon if it follows text.
+	var nodes []*node
+	switch trimmed := strings.TrimSuffix(text, "::"); {
+	case len(strings.TrimSpace(trimmed)) == 0:
+	case strings.HasSuffix(trimmed, " ") || strings.HasSuffix(trimmed, "\n"):
+		nodes = append(nodes, &node{kind: kindParagraph, line: lines[start].number, text: strings.TrimRightFunc(trimmed, unicode.IsSpace)})
+	default:
+		nodes = append(nodes, &node{kind: kindParagraph, line: lines[start].number, text: trimmed + ":"})
+	}
+
+	for i < len(lines) && isBlank(lines[i]) {
+		i++
+	}
+
+	if i < len(lines) && indentOf(lines[i].text) > 0 {
+		block, end := indented(lines, i, 1)
+		literal := &node{kind: kindLiteral, line: lines[i].number, text: joinLines(block)}
+		if len(self.language) > 0 {
+			literal.kind = kindCode
+			literal.class = self.language
+		}
+
+		return append(nodes, literal), end, nil
+	}
+
+	return nodes, i, nil
+}
+
+// listItem returns the body of a list item whose text starts at the column,
+// along with the index of the line following it.
+func listItem(lines []line, i, column int) ([]line, int) {
+	body := []line{{"", lines[i].number}}
+	if column < len(lines[i].text) {
+		body[0].text = lines[i].text[column:]
+	}
+
+	rest, end := indented(lines, i+1, column)
+	if len(rest) > 0 {
+		for _, l := range lines[i+1 : end] {
+			if isBlank(l) {
+				body = append(body, l)
+			} else {
+				body = append(body, line{l.text[column:], l.number})
+			}
+		}
+	}
+
+	for len(body) > 0 && isBlank(body[len(body)-1]) {
+		body = body[:len(body)-1]
+	}
+
+	return body, end
+}
+
+func (self *parser) parseList(lines []line, i int, kind nodeKind) ([]*node, int, error) {
+	list := &node{kind: kind, line: lines[i].number}
+
+	var bullet, suffix string
+	for i < len(lines) {
+		text := lines[i].text
+
+		var column int
+		if kind == kindBulletList {
+			matches := bulletExp.FindStringSubmatch(text)
+			if matches == nil || len(bullet) > 0 && matches[1] != bullet {
+				break
+			}
+
+			bullet = matches[1]
+			column = len(matches[0])
+		} else {
+			matches := enumExp.FindStringSubmatch(text)
+			if matches == nil || len(suffix) > 0 && matches[1]+matches[3] != suffix {
+				break
+			}
+
+			if matches[1] == "(" && matches[3] != ")" {
+				break
+			}
+
+			if len(list.children) == 0 {
+				list.class, list.attrs = enumStyle(matches[2])
+			}
+
+			suffix = matches[1] + matches[3]
+			column = len(matches[0])
+		}
+
+		if column == len(text) {
+			column = len(text) + 1
+			if i+1 < len(lines) && !isBlank(lines[i+1]) && indentOf(lines[i+1].text) > 0 {
+				column = indentOf(lines[i+1].text)
+			}
+		}
+
+		body, end := listItem(lines, i, column)
+		children, err := self.parseBlocks(body)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		list.children = append(list.children, &node{kind: kindListItem, line: lines[i].number, children: children})
+
+		for i = end; i < len(lines) && isBlank(lines[i]); i++ {
+		}
+	}
+
+	return []*node{list}, i, nil
+}
+
+func enumStyle(enumerator string) (string, map[string]string) {
+	attrs := make(map[string]string)
+	switch {
+	case enumerator == "#":
+	case unicode.IsDigit(rune(enumerator[0])):
+		if start, err := strconv.Atoi(enumerator); err == nil && start != 1 {
+			attrs["start"] = strconv.Itoa(start)
+		}
+	case unicode.IsUpper(rune(enumerator[0])):
+		attrs["type"] = "A"
+		if enumerator != "A" {
+			attrs["start"] = strconv.Itoa(int(enumerator[0]-'A') + 1)
+		}
+	default:
+		attrs["type"] = "a"
+		if enumerator != "a" {
+			attrs["start"] = strconv.Itoa(int(enumerator[0]-'a') + 1)
+		}
+	}
+
+	return "", attrs
+}
+
+func (self *parser) parseDefinitionList(lines []line, i int) ([]*node, int, error) {
+	list := &node{kind: kindDefinitionList, line: lines[i].number}
+	for i+1 < len(lines) && !isBlank(lines[i]) && indentOf(lines[i].text) == 0 && indentOf(lines[i+1].text) > 0 && !isBlank(lines[i+1]) {
+		term := lines[i]
+		block, end := indented(lines, i+1, 1)
+		children, err := self.parseBlocks(block)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		// A classifier may follow the term, separated by " : ".
+		definition := &node{kind: kindDefinition, line: term.number, text: term.text, children: children}
+		if index := strings.Index(term.text, " : "); index >= 0 {
+			definition.text = term.text[:index]
+			definition.class = strings.TrimSpace(term.text[index+3:])
+		}
+
+		list.children = append(list.children, definition)
+		for i = end; i < len(lines) && isBlank(lines[i]); i++ {
+		}
+	}
+
+	return []*node{list}, i, nil
+}
+
+func (self *parser) parseFieldList(lines []line, i int) ([]*node, int, error) {
+	list := &node{kind: kindFieldList, line: lines[i].number}
+	for i < len(lines) {
+		matches := fieldExp.FindStringSubmatch(lines[i].text)
+		if matches == nil {
+			break
+		}
+
+		body := []line{{strings.TrimSpace(lines[i].text[len(matches[0]):]), lines[i].number}}
+		rest, end := indented(lines, i+1, 1)
+		body = append(body, rest...)
+		children, err := self.parseBlocks(body)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		list.children = append(list.children, &node{kind: kindField, line: lines[i].number, text: matches[1], children: children})
+		for i = end; i < len(lines) && isBlank(lines[i]); i++ {
+		}
+	}
+
+	return []*node{list}, i, nil
+}
+
+func (self *parser) parseLineBlock(lines []line, i int) ([]*node, int, error) {
+	block := &node{kind: kindLineBlock, line: lines[i].number}
+	for i < len(lines) && (strings.HasPrefix(lines[i].text, "| ") || lines[i].text == "|") {
+		text := strings.TrimPrefix(strings.TrimPrefix(lines[i].text, "|"), " ")
+		i++
+
+		// Continuation lines are indented and joined to the previous line.
+		for i < len(lines) && !isBlank(lines[i]) && indentOf(lines[i].text) > 0 {
+			text += " " + strings.TrimSpace(lines[i].text)
+			i++
+		}
+
+		block.children = append(block.children, &node{kind: kindParagraph, line: lines[i-1].number, text: text})
+	}
+
+	return []*node{block}, i, nil
+}
+
+// parseCell parses the content of a table cell.
+func (self *parser) parseCell(lines []line) (*node, error) {
+	for len(lines) > 0 && isBlank(lines[0]) {
+		lines = lines[1:]
+	}
+
+	for len(lines) > 0 && isBlank(lines[len(lines)-1]) {
+		lines = lines[:len(lines)-1]
+	}
+
+	children, err := self.parseBlocks(dedent(lines))
+	if err != nil {
+		return nil, err
+	}
+
+	return &node{kind: kindListItem, children: children}, nil
+}
+
+func (self *parser) parseGridTable(lines []line, i int) ([]*node, int, error) {
+	start := i
+	for i < len(lines) && (strings.HasPrefix(lines[i].text, "+") || strings.HasPrefix(lines[i].text, "|")) {
+		i++
+	}
+
+	border := lines[start].text
+	var columns []int
+	for index, c := range border {
+		if c == '+' {
+			columns = append(columns, index)
+		}
+	}
+
+	table := &node{kind: kindTable, line: lines[start].number}
+	var (
+		cells  [][]line
+		spans  []int
+		inRow  bool
+		header = -1
+	)
+
+	flush := func() error {
+		if !inRow {
+			return nil
+		}
+
+		var row []*node
+		for index, cellLines := range cells {
+			cell, err := self.parseCell(cellLines)
+			if err != nil {
+				return err
+			}
+
+			if spans[index] > 1 {
+				cell.attrs = map[string]string{"colspan": strconv.Itoa(spans[index])}
+			}
+
+			row = append(row, cell)
+		}
+
+		table.rows = append(table.rows, row)
+		inRow = false
+		return nil
+	}
+
+	for _, current := range lines[start+1 : i] {
+		text := current.text
+		if len(text) != len(border) || text[len(text)-1] != text[0] {
+			return nil, 0, &Error{current.number, "malformed grid table"}
+		}
+
+		if strings.HasPrefix(text, "+") {
+			if !gridBorderExp.MatchString(text) {
+				return nil, 0, &Error{current.number, "row spans in grid tables are not supported"}
+			}
+
+			if err := flush(); err != nil {
+				return nil, 0, err
+			}
+
+			if strings.Contains(text, "=") {
+				if header >= 0 {
+					return nil, 0, &Error{current.number, "grid table has multiple header separators"}
+				}
+
+				header = len(table.rows)
+			}
+
+			continue
+		}
+
+		// Cell boundaries are taken from the first line of each row, so that
+		// cells may span columns.
+		if !inRow {
+			cells, spans = nil, nil
+			last := 0
+			for index, column := range columns[1:] {
+				if text[column] == '|' {
+					cells = append(cells, nil)
+					spans = append(spans, index+1-last)
+					last = index + 1
+				}
+			}
+
+			inRow = true
+		}
+
+		cell, last := 0, columns[0]
+		for _, column := range columns[1:] {
+			if text[column] != '|' {
+				continue
+			}
+
+			if cell < len(cells) {
+				cells[cell] = append(cells[cell], line{strings.TrimRight(text[last+1:column], " "), current.number})
+			}
+
+			cell, last = cell+1, column
+		}
+	}
+
+	if err := flush(); err != nil {
+		return nil, 0, err
+	}
+
+	if header > 0 {
+		table.header = header
+	}
+
+	return []*node{table}, i, nil
+}
+
+func (self *parser) parseSimpleTable(lines []line, i int) ([]*node, int, error) {
+	border := lines[i].text
+
+	var columns [][2]int
+	for index := 0; index < len(border); {
+		if border[index] == '=' {
+			end := index
+			for end < len(border) && border[end] == '=' {
+				end++
+			}
+
+			columns = append(columns, [2]int{index, end})
+			index = end
+		} else {
+			index++
+		}
+	}
+
+	table := &node{kind: kindTable, line: lines[i].number}
+	var (
+		rows    [][][]line
+		borders int
+	)
+
+	i++
+	for ; i < len(lines); i++ {
+		text := lines[i].text
+		if simpleExp.MatchString(text) || text == strings.Repeat("=", len(text)) && len(text) > 0 {
+			borders++
+			if i+1 >= len(lines) || isBlank(lines[i+1]) {
+				i++
+				break
+			}
+
+			table.header = len(rows)
+			continue
+		}
+
+		if isBlank(lines[i]) {
+			continue
+		}
+
+		// Lines with an empty first column continue the previous row.
+		cells := make([][]line, len(columns))
+		for index, column := range columns {
+			start, end := column[0], column[1]
+			if index == len(columns)-1 {
+				end = len(text)
+			} else if end < len(text) && text[end] != ' ' {
+				return nil, 0, &Error{lines[i].number, "text in simple table crosses a column boundary"}
+			}
+
+			if start < len(text) {
+				cells[index] = []line{{strings.TrimRight(text[start:min(end, len(text))], " "), lines[i].number}}
+			}
+		}
+
+		if len(rows) > 0 && (len(cells[0]) == 0 || isBlank(cells[0][0])) {
+			row := rows[len(rows)-1]
+			for index := range row {
+				row[index] = append(row[index], cells[index]...)
+			}
+		} else {
+			rows = append(rows, cells)
+		}
+	}
+
+	if borders == 0 {
+		return nil, 0, &Error{table.line, "simple table is not terminated"}
+	}
+
+	for _, cells := range rows {
+		var row []*node
+		for _, cellLines := range cells {
+			cell, err := self.parseCell(cellLines)
+			if err != nil {
+				return nil, 0, err
+			}
+
+			row = append(row, cell)
+		}
+
+		table.rows = append(table.rows, row)
+	}
+
+	return []*node{table}, i, nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,426 @@
+package rst
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+)
+
+type renderer struct {
+	parser        *parser
+	buff          bytes.Buffer
+	anonymous     int
+	autoFootnotes int
+}
+
+// convert renders a reStructuredText document to HTML.
+func convert(source []byte) ([]byte, error) {
+	parser := newParser()
+	nodes, err := parser.parseBlocks(splitLines(string(source)))
+	if err != nil {
+		return nil, err
+	}
+
+	renderer := &renderer{parser: parser}
+	if err := renderer.blocks(nodes, false); err != nil {
+		return nil, err
+	}
+
+	return renderer.buff.Bytes(), nil
+}
+
+func (self *renderer) write(format string, args ...interface{}) {
+	fmt.Fprintf(&self.buff, format, args...)
+}
+
+// blocks renders a sequence of nodes; compact paragraphs are rendered without
+// enclosing "<p>" elements, as in simple lists and table cells.
+func (self *renderer) blocks(nodes []*node, compact bool) error {
+	for _, node := range nodes {
+		if err := self.block(node, compact); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (self *renderer) block(node *node, compact bool) error {
+	switch node.kind {
+	case kindHeading:
+		text, err := self.inline(node.text, node.line)
+		if err != nil {
+			return err
+		}
+
+		level := node.level
+		if level > 6 {
+			level = 6
+		}
+
+		self.write("<h%d id=\"%s\">%s</h%d>\n", level, html.EscapeString(node.id), text, level)
+	case kindParagraph:
+		text, err := self.inline(node.text, node.line)
+		if err != nil {
+			return err
+		}
+
+		if compact {
+			self.write("%s\n", text)
+		} else {
+			self.write("<p>%s</p>\n", text)
+		}
+	case kindLiteral:
+		self.write("<pre>%s\n</pre>\n", html.EscapeString(node.text))
+	case kindCode:
+		if len(node.class) > 0 {
+			self.write("<pre><code class=\"language-%s\">%s\n</code></pre>\n", html.EscapeString(node.class), html.EscapeString(node.text))
+		} else {
+			self.write("<pre><code>%s\n</code></pre>\n", html.EscapeString(node.text))
+		}
+	case kindMath:
+		self.write("<div class=\"math\">%s</div>\n", html.EscapeString(node.text))
+	case kindRaw:
+		self.write("%s\n", node.text)
+	case kindBlockQuote:
+		return self.container("<blockquote>\n", node.children, "</blockquote>\n")
+	case kindBulletList, kindEnumList:
+		return self.list(node)
+	case kindDefinitionList:
+		self.write("<dl>\n")
+		for _, item := range node.children {
+			term, err := self.inline(item.text, item.line)
+			if err != nil {
+				return err
+			}
+
+			if len(item.class) > 0 {
+				classifier, err := self.inline(item.class, item.line)
+				if err != nil {
+					return err
+				}
+
+				term += " <span class=\"classifier\">" + classifier + "</span>"
+			}
+
+			self.write("<dt>%s</dt>\n", term)
+			if err := self.container("<dd>\n", item.children, "</dd>\n"); err != nil {
+				return err
+			}
+		}
+
+		self.write("</dl>\n")
+	case kindFieldList:
+		self.write("<dl class=\"field-list\">\n")
+		for _, field := range node.children {
+			name, err := self.inline(field.text, field.line)
+			if err != nil {
+				return err
+			}
+
+			self.write("<dt>%s</dt>\n<dd>", name)
+			if err := self.items(field.children); err != nil {
+				return err
+			}
+
+			self.write("</dd>\n")
+		}
+
+		self.write("</dl>\n")
+	case kindLineBlock:
+		self.write("<div class=\"line-block\">\n")
+		for _, line := range node.children {
+			text, err := self.inline(line.text, line.line)
+			if err != nil {
+				return err
+			}
+
+			self.write("<div class=\"line\">%s</div>\n", text)
+		}
+
+		self.write("</div>\n")
+	case kindTable:
+		return self.table(node)
+	case kindTransition:
+		self.write("<hr>\n")
+	case kindAdmonition:
+		title, err := self.inline(node.text, node.line)
+		if err != nil {
+			return err
+		}
+
+		open := fmt.Sprintf("<aside class=\"admonition %s\">\n<p class=\"admonition-title\">%s</p>\n", html.EscapeString(node.class), title)
+		return self.container(open, node.children, "</aside>\n")
+	case kindTopic:
+		title, err := self.inline(node.text, node.line)
+		if err != nil {
+			return err
+		}
+
+		open := fmt.Sprintf("<aside class=\"%s\">\n<p class=\"%s-title\">%s</p>\n", node.class, node.class, title)
+		return self.container(open, node.children, "</aside>\n")
+	case kindRubric:
+		text, err := self.inline(node.text, node.line)
+		if err != nil {
+			return err
+		}
+
+		self.write("<p class=\"rubric\">%s</p>\n", text)
+	case kindImage:
+		self.image(node)
+		self.write("\n")
+	case kindFigure:
+		self.write("<figure>\n")
+		self.image(node.children[0])
+		self.write("\n")
+		if len(node.children) > 1 {
+			caption := node.children[1]
+			if caption.kind == kindParagraph {
+				text, err := self.inline(caption.text, caption.line)
+				if err != nil {
+					return err
+				}
+
+				self.write("<figcaption>%s</figcaption>\n", text)
+			}
+
+			if len(node.children) > 2 {
+				if err := self.container("<div class=\"legend\">\n", node.children[2:], "</div>\n"); err != nil {
+					return err
+				}
+			}
+		}
+
+		self.write("</figure>\n")
+	case kindTarget:
+		self.write("<span id=\"%s\"></span>\n", html.EscapeString(node.id))
+	case kindFootnote:
+		class := "footnote"
+		if len(node.class) > 0 {
+			class = node.class
+		}
+
+		open := fmt.Sprintf("<aside class=\"%s\" id=\"%s\">\n<span class=\"label\">[%s]</span>\n", class, html.EscapeString(node.id), html.EscapeString(node.text))
+		return self.container(open, node.children, "</aside>\n")
+	case kindContents:
+		return self.contents(node)
+	}
+
+	return nil
+}
+
+func (self *renderer) container(open string, children []*node, close string) error {
+	self.buff.WriteString(open)
+	if err := self.blocks(children, false); err != nil {
+		return err
+	}
+
+	self.buff.WriteString(close)
+	return nil
+}
+
+// isSimple checks if the content of an item consists of at most one paragraph,
+// optionally followed by a nested list, so that it can be rendered compactly.
+func isSimple(children []*node) bool {
+	if len(children) == 0 {
+		return true
+	}
+
+	if children[0].kind != kindParagraph {
+		return false
+	}
+
+	for _, child := range children[1:] {
+		if child.kind != kindBulletList && child.kind != kindEnumList {
+			return false
+		}
+	}
+
+	return true
+}
+
+// items renders the content of a list item, field or table cell.
+func (self *renderer) items(children []*node) error {
+	if !isSimple(children) {
+		self.write("\n")
+		return self.blocks(children, false)
+	}
+
+	if len(children) == 0 {
+		return nil
+	}
+
+	text, err := self.inline(children[0].text, children[0].line)
+	if err != nil {
+		return err
+	}
+
+	self.buff.WriteString(text)
+	if len(children) > 1 {
+		self.write("\n")
+		return self.blocks(children[1:], false)
+	}
+
+	return nil
+}
+
+func (self *renderer) list(node *node) error {
+	tag := "ul"
+	if node.kind == kindEnumList {
+		tag = "ol"
+	}
+
+	self.write("<%s", tag)
+	var keys []string
+	for key := range node.attrs {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	for _, key := range keys {
+		self.write(" %s=\"%s\"", key, html.EscapeString(node.attrs[key]))
+	}
+
+	self.write(">\n")
+	for _, item := range node.children {
+		self.write("<li>")
+		if err := self.items(item.children); err != nil {
+			return err
+		}
+
+		self.write("</li>\n")
+	}
+
+	self.write("</%s>\n", tag)
+	return nil
+}
+
+func (self *renderer) table(node *node) error {
+	self.write("<table>\n")
+	for index, row := range node.rows {
+		if index == 0 && node.header > 0 {
+			self.write("<thead>\n")
+		}
+
+		if index == node.header {
+			self.write("<tbody>\n")
+		}
+
+		cell := "td"
+		if index < node.header {
+			cell = "th"
+		}
+
+		self.write("<tr>\n")
+		for _, column := range row {
+			self.write("<%s", cell)
+			if colspan, ok := column.attrs["colspan"]; ok {
+				self.write(" colspan=\"%s\"", colspan)
+			}
+
+			self.write(">")
+			if err := self.items(column.children); err != nil {
+				return err
+			}
+
+			self.write("</%s>\n", cell)
+		}
+
+		self.write("</tr>\n")
+		if index == node.header-1 {
+			self.write("</thead>\n")
+		}
+	}
+
+	if len(node.rows) > node.header {
+		self.write("</tbody>\n")
+	}
+
+	self.write("</table>\n")
+	return nil
+}
+
+func (self *renderer) image(node *node) {
+	attrs := node.attrs
+	alt, ok := attrs["alt"]
+	if !ok {
+		alt = attrs["src"]
+	}
+
+	var img strings.Builder
+	fmt.Fprintf(&img, "<img src=\"%s\" alt=\"%s\"", html.EscapeString(attrs["src"]), html.EscapeString(alt))
+	for _, key := range []string{"width", "height", "class"} {
+		if value, ok := attrs[key]; ok {
+			fmt.Fprintf(&img, " %s=\"%s\"", key, html.EscapeString(value))
+		}
+	}
+
+	img.WriteString(">")
+	if target, ok := attrs["target"]; ok {
+		self.write("<a href=\"%s\">%s</a>", html.EscapeString(rewriteLink(target)), img.String())
+	} else {
+		self.buff.WriteString(img.String())
+	}
+}
+
+// contents renders a table of contents of the sections which follow it, so
+// that the title of the document is not included.
+func (self *renderer) contents(contents *node) error {
+	title, err := self.inline(contents.text, contents.line)
+	if err != nil {
+		return err
+	}
+
+	var headings []*node
+	base := 0
+	for _, heading := range self.parser.headings {
+		if heading.line > contents.line {
+			headings = append(headings, heading)
+			if base == 0 || heading.level < base {
+				base = heading.level
+			}
+		}
+	}
+
+	self.write("<nav class=\"contents\">\n<p class=\"contents-title\">%s</p>\n", title)
+
+	depth := 0
+	for _, heading := range headings {
+		level := heading.level - base + 1
+		if level > contents.level {
+			continue
+		}
+
+		text, err := self.inline(heading.text, heading.line)
+		if err != nil {
+			return err
+		}
+
+		if level > depth {
+			for ; depth < level; depth++ {
+				if depth > 0 {
+					self.write("\n")
+				}
+
+				self.write("<ul>\n<li>")
+			}
+		} else {
+			for ; depth > level; depth-- {
+				self.write("</li>\n</ul>\n")
+			}
+
+			self.write("</li>\n<li>")
+		}
+
+		self.write("<a href=\"#%s\">%s</a>", html.EscapeString(heading.id), text)
+	}
+
+	for ; depth > 0; depth-- {
+		self.write("</li>\n</ul>\n")
+	}
+
+	self.write("</nav>\n")
+	return nil
+}
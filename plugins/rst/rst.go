@@ -0,0 +1,75 @@
+// Package rst renders reStructuredText documents to HTML. As with the
+// "markdown" plugin, metadata is not extracted automatically; use the
+// "frontmatter" plugin to read any which may be present in your source content.
+//
+// A solid subset of reStructuredText is supported: sections, transitions,
+// paragraphs, block quotes, bullet, enumerated, definition and field lists,
+// line blocks, literal blocks, grid and simple tables, inline markup and roles,
+// hyperlinks and targets, footnotes, citations and substitutions. The following
+// directives are understood: admonitions such as "note" and "warning",
+// "admonition", "topic", "sidebar", "rubric", "code-block", "highlight",
+// "math", "raw" (HTML only), "image", "figure", "contents", "list-table" and
+// "csv-table". Sphinx directives such as "toctree" and "index" are ignored.
+//
+// Unsupported directives, unknown references and malformed tables cause the
+// build to fail with an error pointing at the offending line, rather than being
+// silently dropped from the output.
+//
+// Relative links to other reStructuredText files are rewritten to point to the
+// HTML files they are rendered to.
+package rst
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+
+	"foosoft.net/projects/goldsmith"
+	"foosoft.net/projects/goldsmith-components/filters/wildcard"
+)
+
+// Rst chainable context.
+type Rst struct{}
+
+// New creates a new instance of the Rst plugin.
+func New() *Rst {
+	return &Rst{}
+}
+
+func (*Rst) Name() string {
+	return "rst"
+}
+
+func (*Rst) Initialize(context *goldsmith.Context) error {
+	context.Filter(wildcard.New("**/*.rst", "**/*.rest"))
+	return nil
+}
+
+func (*Rst) Process(context *goldsmith.Context, inputFile *goldsmith.File) error {
+	outputPath := strings.TrimSuffix(inputFile.Path(), path.Ext(inputFile.Path())) + ".html"
+	if outputFile := context.RetrieveCachedFile(outputPath, inputFile); outputFile != nil {
+		outputFile.CopyProps(inputFile)
+		context.DispatchFile(outputFile)
+		return nil
+	}
+
+	var dataIn bytes.Buffer
+	if _, err := dataIn.ReadFrom(inputFile); err != nil {
+		return err
+	}
+
+	dataOut, err := convert(dataIn.Bytes())
+	if err != nil {
+		return fmt.Errorf("%s: %w", inputFile.Path(), err)
+	}
+
+	outputFile, err := context.CreateFileFromReader(outputPath, bytes.NewReader(dataOut))
+	if err != nil {
+		return err
+	}
+
+	outputFile.CopyProps(inputFile)
+	context.DispatchAndCacheFile(outputFile, inputFile)
+	return nil
+}
@@ -0,0 +1,55 @@
+package rst
+
+import (
+	"errors"
+	"testing"
+
+	"foosoft.net/projects/goldsmith"
+	"foosoft.net/projects/goldsmith-components/harness"
+)
+
+func Test(self *testing.T) {
+	harness.Validate(
+		self,
+		func(gs *goldsmith.Goldsmith) {
+			gs.Chain(New())
+		},
+	)
+}
+
+func TestErrors(self *testing.T) {
+	cases := []struct {
+		source string
+		line   int
+		err    string
+	}{
+		{"Text\n\n.. unknown:: argument\n", 3, `unsupported directive "unknown"`},
+		{"A link_ to nowhere.\n", 1, `unknown target "link"`},
+		{"See [3]_.\n", 1, `unknown footnote or citation [3]`},
+		{"Hello |name|.\n", 1, `undefined substitution "name"`},
+		{"+---+---+\n| a | b |\n+   +---+\n| c | d |\n+---+---+\n", 3, `row spans in grid tables are not supported`},
+	}
+
+	for _, c := range cases {
+		_, err := convert([]byte(c.source))
+
+		var rstErr *Error
+		if !errors.As(err, &rstErr) {
+			self.Errorf("%q: expected rst error, got %v", c.source, err)
+			continue
+		}
+
+		if rstErr.Line != c.line || rstErr.Message != c.err {
+			self.Errorf("%q: unexpected error %v", c.source, err)
+		}
+	}
+}
+
+func TestEmptyItems(self *testing.T) {
+	sources := []string{"*\n", "-\n", "1.\n", "#.\n", "* \n", "* item\n*\n", "- a\n-\n\ntext\n"}
+	for _, source := range sources {
+		if _, err := convert([]byte(source)); err != nil {
+			self.Errorf("%q: unexpected error %v", source, err)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package rule
 
 import (
+	"fmt"
 	"io/ioutil"
 	"path/filepath"
 	"sync"
@@ -15,6 +16,10 @@ type rule struct {
 	Accept  []string
 	Reject  []string
 	baseDir string
+
+	scopeWildcard  *wildcard.Wildcard
+	acceptWildcard *wildcard.Wildcard
+	rejectFilter   goldsmith.Filter
 }
 
 type ruleApply struct {
@@ -26,18 +31,18 @@ type ruleDrop struct {
 	rule
 }
 
-func (self *rule) accept(inputFile *goldsmith.File) bool {
-	if !wildcard.New(filepath.Join(self.baseDir, "**")).Accept(inputFile) {
-		return false
-	}
+func (self *rule) compile(baseDir string) error {
+	self.baseDir = baseDir
+	self.scopeWildcard = wildcard.New(filepath.Join(self.baseDir, "**"))
 
 	var acceptPaths []string
 	for _, path := range self.Accept {
 		acceptPaths = append(acceptPaths, filepath.Join(self.baseDir, path))
 	}
 
-	if wildcard.New(acceptPaths...).Accept(inputFile) {
-		return true
+	self.acceptWildcard = wildcard.New(acceptPaths...)
+	if err := self.acceptWildcard.Validate(); err != nil {
+		return err
 	}
 
 	var rejectPaths []string
@@ -45,11 +50,32 @@ func (self *rule) accept(inputFile *goldsmith.File) bool {
 		rejectPaths = append(rejectPaths, filepath.Join(self.baseDir, path))
 	}
 
-	if len(rejectPaths) == 0 {
+	if len(rejectPaths) > 0 {
+		rejectWildcard := wildcard.New(rejectPaths...)
+		if err := rejectWildcard.Validate(); err != nil {
+			return err
+		}
+
+		self.rejectFilter = operator.Not(rejectWildcard)
+	}
+
+	return nil
+}
+
+func (self *rule) accept(inputFile *goldsmith.File) bool {
+	if !self.scopeWildcard.Accept(inputFile) {
+		return false
+	}
+
+	if self.acceptWildcard.Accept(inputFile) {
+		return true
+	}
+
+	if self.rejectFilter == nil {
 		return false
 	}
 
-	return operator.Not(wildcard.New(rejectPaths...)).Accept(inputFile)
+	return self.rejectFilter.Accept(inputFile)
 }
 
 func (self *ruleApply) apply(inputFile *goldsmith.File) {
@@ -81,11 +107,15 @@ func newRuleSet(inputFile *goldsmith.File) (*ruleSet, error) {
 	}
 
 	for _, rule := range ruleSet.Apply {
-		rule.baseDir = inputFile.Dir()
+		if err := rule.compile(inputFile.Dir()); err != nil {
+			return nil, fmt.Errorf("%s: %w", inputFile.Path(), err)
+		}
 	}
 
 	for _, rule := range ruleSet.Drop {
-		rule.baseDir = inputFile.Dir()
+		if err := rule.compile(inputFile.Dir()); err != nil {
+			return nil, fmt.Errorf("%s: %w", inputFile.Path(), err)
+		}
 	}
 
 	return &ruleSet, nil
@@ -1,12 +1,14 @@
 package rule
 
 import (
+	"errors"
 	"testing"
 
 	"foosoft.net/projects/goldsmith"
 	"foosoft.net/projects/goldsmith-components/harness"
 	"foosoft.net/projects/goldsmith-components/plugins/frontmatter"
 	"foosoft.net/projects/goldsmith-components/plugins/layout"
+	"github.com/bmatcuk/doublestar/v4"
 )
 
 func Test(self *testing.T) {
@@ -20,3 +22,13 @@ func Test(self *testing.T) {
 		},
 	)
 }
+
+func TestMalformed(self *testing.T) {
+	errs := goldsmith.Begin("testdata/malformed/source").
+		Chain(New()).
+		End(self.TempDir())
+
+	if len(errs) != 1 || !errors.Is(errs[0], doublestar.ErrBadPattern) {
+		self.Fatalf("expected malformed pattern error, got %v", errs)
+	}
+}
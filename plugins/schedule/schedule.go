@@ -0,0 +1,71 @@
+// Package schedule drops files which are not published at build time, based on
+// publication and expiry dates stored in their metadata. Files with dates that
+// cannot be interpreted cause the build to fail. See the "schedule" filter for
+// details on how dates are evaluated.
+package schedule
+
+import (
+	"time"
+
+	"foosoft.net/projects/goldsmith"
+	"foosoft.net/projects/goldsmith-components/filters/schedule"
+)
+
+// Schedule chainable context.
+type Schedule struct {
+	filter *schedule.Schedule
+}
+
+// New creates a new instance of the Schedule plugin.
+func New() *Schedule {
+	return &Schedule{schedule.New()}
+}
+
+// DateKey sets the metadata key used to access the content date (default: "Date").
+// This date is used as the publication date if none is specified explicitly.
+func (self *Schedule) DateKey(key string) *Schedule {
+	self.filter.DateKey(key)
+	return self
+}
+
+// PublishDateKey sets the metadata key used to access the publication date (default: "PublishDate").
+func (self *Schedule) PublishDateKey(key string) *Schedule {
+	self.filter.PublishDateKey(key)
+	return self
+}
+
+// ExpiryDateKey sets the metadata key used to access the expiry date (default: "ExpiryDate").
+func (self *Schedule) ExpiryDateKey(key string) *Schedule {
+	self.filter.ExpiryDateKey(key)
+	return self
+}
+
+// IncludeFuture sets whether files with future publication dates are kept (default: false).
+// This is useful for previewing scheduled content.
+func (self *Schedule) IncludeFuture(includeFuture bool) *Schedule {
+	self.filter.IncludeFuture(includeFuture)
+	return self
+}
+
+// ReferenceTime sets the time that dates are compared against (default: the current time).
+func (self *Schedule) ReferenceTime(referenceTime time.Time) *Schedule {
+	self.filter.ReferenceTime(referenceTime)
+	return self
+}
+
+func (*Schedule) Name() string {
+	return "schedule"
+}
+
+func (self *Schedule) Process(context *goldsmith.Context, inputFile *goldsmith.File) error {
+	published, err := self.filter.Check(inputFile)
+	if err != nil {
+		return err
+	}
+
+	if published {
+		context.DispatchFile(inputFile)
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"foosoft.net/projects/goldsmith"
+	"foosoft.net/projects/goldsmith-components/harness"
+	"foosoft.net/projects/goldsmith-components/plugins/frontmatter"
+)
+
+func TestDefault(self *testing.T) {
+	harness.ValidateCase(
+		self,
+		"default",
+		func(gs *goldsmith.Goldsmith) {
+			gs.
+				Chain(frontmatter.New()).
+				Chain(New().ReferenceTime(time.Date(2020, 6, 1, 0, 0, 0, 0, time.Local)))
+		},
+	)
+}
+
+func TestFuture(self *testing.T) {
+	harness.ValidateCase(
+		self,
+		"future",
+		func(gs *goldsmith.Goldsmith) {
+			gs.
+				Chain(frontmatter.New()).
+				Chain(New().ReferenceTime(time.Date(2020, 6, 1, 0, 0, 0, 0, time.Local)).IncludeFuture(true))
+		},
+	)
+}
@@ -0,0 +1,206 @@
+// Package stats computes reading statistics for HTML files, storing the word
+// count, character count and estimated reading time of their text as props.
+// This plugin is useful when combined with other plugins such as "collection"
+// to show reading times in blog post listings.
+//
+// Only text within the body is counted, excluding code blocks, scripts and
+// styles. Chinese and Japanese text, which is written without spaces between
+// words, is counted per character and read at a separate rate.
+package stats
+
+import (
+	"math"
+	"unicode"
+
+	"foosoft.net/projects/goldsmith"
+	"foosoft.net/projects/goldsmith-components/filters/wildcard"
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Stats chainable context.
+type Stats struct {
+	wordCountKey   string
+	charCountKey   string
+	readingTimeKey string
+	excludePath    string
+	wordsPerMinute int
+	charsPerMinute int
+}
+
+// New creates a new instance of the Stats plugin.
+func New() *Stats {
+	return &Stats{
+		wordCountKey:   "WordCount",
+		charCountKey:   "CharCount",
+		readingTimeKey: "ReadingTime",
+		excludePath:    "pre, script, style, template, annotation",
+		wordsPerMinute: 200,
+		charsPerMinute: 500,
+	}
+}
+
+// WordCountKey sets the metadata key used to store the word count (default: "WordCount").
+func (self *Stats) WordCountKey(key string) *Stats {
+	self.wordCountKey = key
+	return self
+}
+
+// CharCountKey sets the metadata key used to store the count of characters
+// other than whitespace (default: "CharCount").
+func (self *Stats) CharCountKey(key string) *Stats {
+	self.charCountKey = key
+	return self
+}
+
+// ReadingTimeKey sets the metadata key used to store the reading time in whole
+// minutes, rounded up (default: "ReadingTime").
+func (self *Stats) ReadingTimeKey(key string) *Stats {
+	self.readingTimeKey = key
+	return self
+}
+
+// ExcludePath sets the CSS path of elements whose text is not counted
+// (default: "pre, script, style, template, annotation").
+func (self *Stats) ExcludePath(path string) *Stats {
+	self.excludePath = path
+	return self
+}
+
+// WordsPerMinute sets the reading speed for text written with spaces between
+// words (default: 200).
+func (self *Stats) WordsPerMinute(words int) *Stats {
+	self.wordsPerMinute = words
+	return self
+}
+
+// CharsPerMinute sets the reading speed for Chinese and Japanese characters
+// (default: 500).
+func (self *Stats) CharsPerMinute(chars int) *Stats {
+	self.charsPerMinute = chars
+	return self
+}
+
+func (*Stats) Name() string {
+	return "stats"
+}
+
+func (*Stats) Initialize(context *goldsmith.Context) error {
+	context.Filter(wildcard.New("**/*.html", "**/*.htm"))
+	return nil
+}
+
+func (self *Stats) Process(context *goldsmith.Context, inputFile *goldsmith.File) error {
+	doc, err := goquery.NewDocumentFromReader(inputFile)
+	if err != nil {
+		return err
+	}
+
+	body := doc.Find("body")
+	if len(self.excludePath) > 0 {
+		body.Find(self.excludePath).Remove()
+	}
+
+	var counter counter
+	counter.countSelection(body)
+	counter.endWord()
+
+	inputFile.SetProp(self.wordCountKey, counter.words+counter.ideographs)
+	inputFile.SetProp(self.charCountKey, counter.chars)
+	inputFile.SetProp(self.readingTimeKey, self.readingTime(counter.words, counter.ideographs))
+
+	context.DispatchFile(inputFile)
+	return nil
+}
+
+func (self *Stats) readingTime(words, ideographs int) int {
+	var minutes float64
+	if self.wordsPerMinute > 0 {
+		minutes += float64(words) / float64(self.wordsPerMinute)
+	}
+
+	if self.charsPerMinute > 0 {
+		minutes += float64(ideographs) / float64(self.charsPerMinute)
+	}
+
+	return int(math.Ceil(minutes))
+}
+
+type counter struct {
+	words      int
+	ideographs int
+	chars      int
+	inWord     bool
+	hasLetter  bool
+}
+
+// inlineElements are the elements which may split a word, such as
+// "<em>un</em>likely"; all other elements separate the text they contain.
+var inlineElements = map[string]bool{
+	"a":      true,
+	"abbr":   true,
+	"b":      true,
+	"code":   true,
+	"del":    true,
+	"em":     true,
+	"i":      true,
+	"ins":    true,
+	"kbd":    true,
+	"mark":   true,
+	"q":      true,
+	"s":      true,
+	"small":  true,
+	"span":   true,
+	"strong": true,
+	"sub":    true,
+	"sup":    true,
+	"u":      true,
+}
+
+func (self *counter) countSelection(selection *goquery.Selection) {
+	selection.Contents().Each(func(i int, child *goquery.Selection) {
+		switch name := goquery.NodeName(child); name {
+		case "#text":
+			self.countText(child.Text())
+		case "#comment":
+		default:
+			if !inlineElements[name] {
+				self.endWord()
+			}
+
+			self.countSelection(child)
+
+			if !inlineElements[name] {
+				self.endWord()
+			}
+		}
+	})
+}
+
+// countText counts words as runs of characters other than whitespace which
+// contain a letter or digit, so that punctuation such as dashes is not counted.
+// Han, Hiragana and Katakana characters are counted individually instead.
+func (self *counter) countText(text string) {
+	for _, c := range text {
+		switch {
+		case unicode.IsSpace(c):
+			self.endWord()
+		case unicode.In(c, unicode.Han, unicode.Hiragana, unicode.Katakana):
+			self.endWord()
+			self.ideographs++
+			self.chars++
+		default:
+			self.inWord = true
+			self.hasLetter = self.hasLetter || unicode.IsLetter(c) || unicode.IsDigit(c)
+			self.chars++
+		}
+	}
+}
+
+func (self *counter) endWord() {
+	if self.inWord && self.hasLetter {
+		self.words++
+	}
+
+	self.inWord = false
+	self.hasLetter = false
+}